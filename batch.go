@@ -0,0 +1,162 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"github.com/golang/protobuf/proto"
+	"io"
+	"strings"
+	"sync"
+)
+
+// CONTENT_HASH_TAG_PREFIX prefixes the hidden tag holding the hash of the content of a file added by AddAll with a FileSpec.Hash.
+// Hidden tags are read with ContentHashes, they are not listed by AllTagsForHash or WatchTags, matched by SearchTag, or added by AddTag.
+const CONTENT_HASH_TAG_PREFIX = "sha256:"
+
+// FileSpec describes a file to be added by AddAll.
+type FileSpec struct {
+	Name string
+	Mime string
+	// Reader supplies the content of the file, no content is written if nil.
+	Reader io.Reader
+	// Hash is the hex encoded SHA-256 hash of the content, recorded in a hidden tag if not empty so ContentHashes can find files already added.
+	Hash string
+}
+
+// ContentHashCallback is triggered with the meta data of a file and the hash of its content.
+type ContentHashCallback func(*bcgo.BlockEntry, *spacego.Meta, string) error
+
+// hiddenTag returns true if the given tag value is hidden from callers listing and searching tags.
+func hiddenTag(value string) bool {
+	return strings.HasPrefix(value, CONTENT_HASH_TAG_PREFIX)
+}
+
+// ADD_ALL_WORKERS is the number of files whose content is written concurrently by AddAll.
+const ADD_ALL_WORKERS = 16
+
+// AddAll adds the given files, returning the reference to each file's meta in the order given.
+// The metas of all files are mined in a single block, then the content of each file is written, mined, and pushed into its own delta channel, followed by the hash of its content if given.
+// Neither the node's cache nor its channels are safe for concurrent use, so workers take turns writing records and mining, and push to peers at the same time.
+// The listener is called from the workers' goroutines, but only by one worker at a time.
+// Once the metas are mined the files exist, so their references are returned even if writing content fails.
+// No files are added if any has a reserved MIME type, or the client's compression is unsupported.
+func (c *spaceClient) AddAll(node bcgo.Node, listener bcgo.MiningListener, files []FileSpec) ([]*bcgo.Reference, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	if c.compression != COMPRESSION_NONE {
+		if _, err := c.codec(c.compression); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		if err := reservedType(f.Mime); err != nil {
+			return nil, err
+		}
+	}
+	account := node.Account()
+	alias := account.Alias()
+	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	c.refresh(node, metas)
+
+	// Write meta data of all files
+	references := make([]*bcgo.Reference, len(files))
+	for i, f := range files {
+		data, err := proto.Marshal(&spacego.Meta{
+			Name: f.Name,
+			Type: encodeType(f.Mime, c.compression),
+		})
+		if err != nil {
+			return nil, err
+		}
+		reference, err := node.Write(bcgo.Timestamp(), metas, []bcgo.Identity{account}, nil, data)
+		if err != nil {
+			return nil, err
+		}
+		references[i] = reference
+	}
+
+	// Mine meta channel once, reporting any failure to push once all files are complete
+	var failure error
+	if err := pushed(c.mine(node, metas, listener), &failure); err != nil {
+		return nil, err
+	}
+
+	// Write content of each file, each into its own delta channel
+	workers := ADD_ALL_WORKERS
+	if workers > len(files) {
+		workers = len(files)
+	}
+	indices := make(chan int)
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	serial := &serialNode{
+		Node: node,
+		lock: &lock,
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				lock.Lock()
+				if err := nodeContext(node).Err(); err != nil {
+					errs[i] = err
+				} else {
+					errs[i] = c.addFile(serial, listener, references[i], files[i])
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for i, f := range files {
+		if f.Reader != nil || f.Hash != "" {
+			indices <- i
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err := pushed(err, &failure); err != nil {
+			return references, err
+		}
+	}
+	return references, failure
+}
+
+// addFile writes the content of the newly added file with the given meta reference, then tags it with the hash of its content if given.
+// A failure to push is reported as ErrNetworkUnavailable, after the content and tag have been mined.
+func (c *spaceClient) addFile(node bcgo.Node, listener bcgo.MiningListener, reference *bcgo.Reference, spec FileSpec) error {
+	var failure error
+	if spec.Reader != nil {
+		if err := pushed(c.addContent(node, listener, reference, spec.Mime, spec.Reader), &failure); err != nil {
+			return err
+		}
+	}
+	if spec.Hash != "" {
+		if _, err := c.addTag(node, listener, reference.RecordHash, []string{CONTENT_HASH_TAG_PREFIX + spec.Hash}); pushed(err, &failure) != nil {
+			return err
+		}
+	}
+	return failure
+}
@@ -24,10 +24,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"github.com/golang/protobuf/proto"
 	"io"
-	"log"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -35,59 +38,168 @@ type SpaceClient interface {
 	bcclientgo.BCClient
 
 	Add(bcgo.Node, bcgo.MiningListener, string, string, io.Reader) (*bcgo.Reference, error)
+	AddAll(bcgo.Node, bcgo.MiningListener, []FileSpec) ([]*bcgo.Reference, error)
 	Amend(bcgo.Node, bcgo.MiningListener, bcgo.Channel, ...*spacego.Delta) error
+	UpdateMeta(bcgo.Node, bcgo.MiningListener, []byte, string, string) error
 	MetaForHash(bcgo.Node, []byte, spacego.MetaCallback) error
 	AllMetas(bcgo.Node, spacego.MetaCallback) error
 	ReadFile(bcgo.Node, []byte) (io.Reader, error)
+	OpenFile(bcgo.Node, []byte) (io.ReadSeekCloser, error)
+	ReadFileRange(bcgo.Node, []byte, int64, int64) (io.Reader, error)
+	ReadFileAt(bcgo.Node, []byte, uint64) (io.Reader, error)
+	ReadFileVersion(bcgo.Node, []byte, []byte) (io.Reader, error)
+	History(bcgo.Node, []byte, HistoryCallback) error
+	Diff(bcgo.Node, []byte, []byte, []byte) (io.Reader, error)
 	WriteFile(bcgo.Node, bcgo.MiningListener, []byte) (io.WriteCloser, error)
+	Revert(bcgo.Node, bcgo.MiningListener, []byte, []byte) error
 	WatchFile(context.Context, bcgo.Node, []byte, func())
+	WatchFileChanges(context.Context, bcgo.Node, []byte, FileChangeCallback) (FileWatch, error)
+	WatchMetas(context.Context, bcgo.Node, spacego.MetaCallback)
 
-	/*
-		AddPreview(bcgo.Node, bcgo.MiningListener, []byte, []string) ([]*bcgo.Reference, error)
-		AllPreviewsForHash(bcgo.Node, []byte, spacego.PreviewCallback) error
-	*/
+	AddPreview(bcgo.Node, bcgo.MiningListener, []byte, []*spacego.Preview) ([]*bcgo.Reference, error)
+	AllPreviewsForHash(bcgo.Node, []byte, spacego.PreviewCallback) error
 
 	AddTag(bcgo.Node, bcgo.MiningListener, []byte, []string) ([]*bcgo.Reference, error)
 	AllTagsForHash(bcgo.Node, []byte, spacego.TagCallback) error
+	RemoveTag(bcgo.Node, bcgo.MiningListener, []byte, []string) ([]*bcgo.Reference, error)
+	WatchTags(context.Context, bcgo.Node, []byte, spacego.TagCallback)
+
+	Share(bcgo.Node, bcgo.MiningListener, []byte, ...string) error
+	SharedMetas(bcgo.Node, SharedMetaCallback) error
+	Revoke(bcgo.Node, bcgo.MiningListener, []byte, ...string) error
+
+	Delete(bcgo.Node, bcgo.MiningListener, []byte) error
+	Restore(bcgo.Node, bcgo.MiningListener, []byte) error
+	Trash(bcgo.Node, spacego.MetaCallback) error
+	EmptyTrash(bcgo.Node, bcgo.MiningListener) error
 
 	SearchMeta(bcgo.Node, spacego.MetaFilter, spacego.MetaCallback) error
 	SearchTag(bcgo.Node, spacego.TagFilter, spacego.MetaCallback) error
+	ContentHashes(bcgo.Node, ContentHashCallback) error
 
 	Registration(string, financego.RegistrationCallback) error
 	Subscription(string, financego.SubscriptionCallback) error
+
+	AddContext(context.Context, bcgo.Node, bcgo.MiningListener, string, string, io.Reader) (*bcgo.Reference, error)
+	AddAllContext(context.Context, bcgo.Node, bcgo.MiningListener, []FileSpec) ([]*bcgo.Reference, error)
+	AmendContext(context.Context, bcgo.Node, bcgo.MiningListener, bcgo.Channel, ...*spacego.Delta) error
+	UpdateMetaContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte, string, string) error
+	MetaForHashContext(context.Context, bcgo.Node, []byte, spacego.MetaCallback) error
+	AllMetasContext(context.Context, bcgo.Node, spacego.MetaCallback) error
+	ReadFileContext(context.Context, bcgo.Node, []byte) (io.Reader, error)
+	OpenFileContext(context.Context, bcgo.Node, []byte) (io.ReadSeekCloser, error)
+	ReadFileRangeContext(context.Context, bcgo.Node, []byte, int64, int64) (io.Reader, error)
+	ReadFileAtContext(context.Context, bcgo.Node, []byte, uint64) (io.Reader, error)
+	ReadFileVersionContext(context.Context, bcgo.Node, []byte, []byte) (io.Reader, error)
+	HistoryContext(context.Context, bcgo.Node, []byte, HistoryCallback) error
+	DiffContext(context.Context, bcgo.Node, []byte, []byte, []byte) (io.Reader, error)
+	WriteFileContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte) (io.WriteCloser, error)
+	RevertContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte, []byte) error
+	AddPreviewContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte, []*spacego.Preview) ([]*bcgo.Reference, error)
+	AllPreviewsForHashContext(context.Context, bcgo.Node, []byte, spacego.PreviewCallback) error
+	AddTagContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte, []string) ([]*bcgo.Reference, error)
+	AllTagsForHashContext(context.Context, bcgo.Node, []byte, spacego.TagCallback) error
+	RemoveTagContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte, []string) ([]*bcgo.Reference, error)
+	ShareContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte, ...string) error
+	SharedMetasContext(context.Context, bcgo.Node, SharedMetaCallback) error
+	RevokeContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte, ...string) error
+	DeleteContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte) error
+	RestoreContext(context.Context, bcgo.Node, bcgo.MiningListener, []byte) error
+	TrashContext(context.Context, bcgo.Node, spacego.MetaCallback) error
+	EmptyTrashContext(context.Context, bcgo.Node, bcgo.MiningListener) error
+	SearchMetaContext(context.Context, bcgo.Node, spacego.MetaFilter, spacego.MetaCallback) error
+	SearchTagContext(context.Context, bcgo.Node, spacego.TagFilter, spacego.MetaCallback) error
+	ContentHashesContext(context.Context, bcgo.Node, ContentHashCallback) error
+	RegistrationContext(context.Context, string, financego.RegistrationCallback) error
+	SubscriptionContext(context.Context, string, financego.SubscriptionCallback) error
 }
 
 type spaceClient struct {
 	bcclientgo.BCClient
+	peers        []string
+	compression  string
+	codecs       map[string]Codec
+	contentCache ContentCache
+	// contentDirectoryLimit is the size of the file system content cache opened under the client's root, zero if not requested.
+	contentDirectoryLimit uint64
+	notifier              notifier
+	logger                Logger
+	hook                  EventHook
+	// events serializes calls to the hook.
+	events sync.Mutex
+	// unreachable holds the error of the last refresh of each channel, if it failed.
+	unreachable sync.Map
+	// files holds the last known state of each of the account's files, see checkFile.
+	files sync.Map
+	// shared holds the references to files shared with each account, see sharedPointers.
+	shared sync.Map
+	// owners holds the files of each owner readable by each account, see ownerFiles.
+	owners sync.Map
+}
+
+// Option configures a SpaceClient.
+type Option func(*spaceClient)
+
+// WithPeers sets the peers the client connects to, SPACE and BC hosts are used if none are given.
+func WithPeers(peers ...string) Option {
+	return func(c *spaceClient) {
+		c.peers = peers
+	}
 }
 
 func NewSpaceClient(peers ...string) SpaceClient {
+	return NewSpaceClientWithOptions(WithPeers(peers...))
+}
+
+func NewSpaceClientWithOptions(options ...Option) SpaceClient {
+	c := &spaceClient{
+		codecs: map[string]Codec{
+			COMPRESSION_GZIP: gzipCodec{},
+			COMPRESSION_ZSTD: zstdCodec{},
+		},
+	}
+	for _, o := range options {
+		o(c)
+	}
+	peers := c.peers
 	if len(peers) == 0 {
 		peers = append(
 			spacego.SpaceHosts(), // Add SPACE host as peer
 			bcgo.BCHost(),        // Add BC host as peer
 		)
 	}
-	return &spaceClient{
-		BCClient: bcclientgo.NewBCClient(peers...),
+	if c.contentCache == nil {
+		c.contentCache = NewMemoryContentCache(DEFAULT_CONTENT_CACHE_LIMIT)
+	}
+	if c.logger == nil {
+		c.logger = defaultLogger{}
 	}
+	c.BCClient = bcclientgo.NewBCClient(peers...)
+	c.openContentDirectory()
+	return c
 }
 
 // Adds file
 func (c *spaceClient) Add(node bcgo.Node, listener bcgo.MiningListener, name, mime string, reader io.Reader) (*bcgo.Reference, error) {
+	if err := reservedType(mime); err != nil {
+		return nil, err
+	}
+	if c.compression != COMPRESSION_NONE {
+		if _, err := c.codec(c.compression); err != nil {
+			return nil, err
+		}
+	}
 	account := node.Account()
 	alias := account.Alias()
 	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
 		return spacego.OpenMetaChannel(alias)
 	})
-	if err := metas.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
-	}
+	c.refresh(node, metas)
 
 	// Create Meta
 	meta := spacego.Meta{
 		Name: name,
-		Type: mime,
+		Type: encodeType(mime, c.compression),
 	}
 
 	data, err := proto.Marshal(&meta)
@@ -101,36 +213,48 @@ func (c *spaceClient) Add(node bcgo.Node, listener bcgo.MiningListener, name, mi
 		return nil, err
 	}
 
-	// Mine meta channel
-	if _, _, err := bcgo.Mine(node, metas, spacego.THRESHOLD_CUSTOMER, listener); err != nil {
+	// Mine meta channel, reporting any failure to push once the file is complete
+	var failure error
+	if err := pushed(c.mine(node, metas, listener), &failure); err != nil {
 		return nil, err
 	}
 
-	if n := node.Network(); n != nil && !reflect.ValueOf(n).IsNil() {
-		// Push update to peers
-		if err := metas.Push(node.Cache(), n); err != nil {
-			log.Println(err)
-		}
-	}
-
 	if reader == nil {
-		return reference, nil
+		return reference, failure
 	}
+	if err := pushed(c.addContent(node, listener, reference, mime, reader), &failure); err != nil {
+		return nil, err
+	}
+	return reference, failure
+}
 
+// addContent writes the content of the newly added file with the given meta reference, and its previews.
+// A failure to push is reported as ErrNetworkUnavailable, after the content has been mined.
+func (c *spaceClient) addContent(node bcgo.Node, listener bcgo.MiningListener, reference *bcgo.Reference, mime string, reader io.Reader) error {
+	account := node.Account()
 	metaId := base64.RawURLEncoding.EncodeToString(reference.RecordHash)
 
 	deltas := node.OpenChannel(spacego.DeltaChannelName(metaId), func() bcgo.Channel {
 		return spacego.OpenDeltaChannel(metaId)
 	})
-	if err := deltas.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
-	}
+	c.refresh(node, deltas)
 
-	// TODO compress data
+	// Keep the start of the data to generate a preview
+	var source *previewBuffer
+	if limit := previewSource(mime); limit > 0 {
+		source = &previewBuffer{
+			limit: limit,
+		}
+		reader = io.TeeReader(reader, source)
+	}
 
 	var last uint64
-	// Read data, create deltas, and write to cache
-	if err := spacego.CreateDeltas(reader, spacego.MAX_SIZE_BYTES, func(delta *spacego.Delta) error {
+	// Read data, create deltas, compress data, and write to cache
+	if err := spacego.CreateDeltas(reader, maxInsert(c.compression), func(delta *spacego.Delta) error {
+		delta, err := c.compressDelta(c.compression, delta)
+		if err != nil {
+			return err
+		}
 		data, err := proto.Marshal(delta)
 		if err != nil {
 			return err
@@ -145,40 +269,84 @@ func (c *spaceClient) Add(node bcgo.Node, listener bcgo.MiningListener, name, mi
 		if err != nil {
 			return err
 		}
-		if _, err := bcgo.WriteRecord(deltas.Name(), node.Cache(), record); err != nil {
+		reference, err := bcgo.WriteRecord(deltas.Name(), node.Cache(), record)
+		if err != nil {
 			return err
 		}
+		c.written(deltas, reference)
 		return nil
 	}); err != nil {
-		return nil, err
+		return err
 	}
 
-	// Mine file channel
-	if _, _, err := bcgo.Mine(node, deltas, spacego.THRESHOLD_CUSTOMER, listener); err != nil {
-		return nil, err
+	// Mine file channel, reporting any failure to push once the previews are added
+	var failure error
+	if err := pushed(c.mine(node, deltas, listener), &failure); err != nil {
+		return err
 	}
 
-	if n := node.Network(); n != nil && !reflect.ValueOf(n).IsNil() {
-		// Push update to peers
-		if err := deltas.Push(node.Cache(), n); err != nil {
-			log.Println(err)
+	// Add preview
+	if source != nil {
+		previews, err := generatePreviews(mime, source.Bytes(), !source.truncated)
+		if err != nil {
+			// Preview is optional so don't fail the file
+			c.logger.Warn("Could not generate preview", "error", err)
+			return failure
+		}
+		channel := node.OpenChannel(spacego.PreviewChannelName(metaId), func() bcgo.Channel {
+			return spacego.OpenPreviewChannel(metaId)
+		})
+		c.refresh(node, channel)
+		if _, err := c.writePreviews(node, listener, channel, []bcgo.Identity{account}, reference, previews); pushed(err, &failure) != nil {
+			return err
 		}
 	}
-
-	// TODO Add preview
-	return reference, nil
+	return failure
 }
 
-// Amend adds the given delta to the file
+// Amend adds the given delta to the file whose deltas are held in the given channel.
+// If no file of the account, or shared with it, holds its deltas in the channel, the deltas are written uncompressed and readable only by the account.
 func (c *spaceClient) Amend(node bcgo.Node, listener bcgo.MiningListener, channel bcgo.Channel, deltas ...*spacego.Delta) error {
 	if len(deltas) == 0 {
 		return nil
 	}
+	name := channel.Name()
+	f, err := c.findFile(node, func(f *file) bool {
+		return f.holds(name)
+	})
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return c.writeDeltas(node, listener, []bcgo.Identity{node.Account()}, COMPRESSION_NONE, channel, deltas...)
+	}
+	return c.amend(node, listener, f, channel, deltas...)
+}
+
+// amend adds the given deltas to the given channel holding the deltas of the given file.
+// Deltas are readable by the aliases granted access to the latest version of the file's meta data, which is only written by the file's owner,
+// so aliases writing records into the delta channel cannot grant themselves access to later changes.
+func (c *spaceClient) amend(node bcgo.Node, listener bcgo.MiningListener, f *file, channel bcgo.Channel, deltas ...*spacego.Delta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	access, err := c.identities(node, f.access())
+	if err != nil {
+		return err
+	}
+	return c.writeDeltas(node, listener, access, f.compression(), channel, deltas...)
+}
+
+// writeDeltas writes the given deltas to the given channel readable by the given identities, compressed with the given compression, and mines the channel.
+func (c *spaceClient) writeDeltas(node bcgo.Node, listener bcgo.MiningListener, access []bcgo.Identity, compression string, channel bcgo.Channel, deltas ...*spacego.Delta) error {
 	account := node.Account()
-	access := []bcgo.Identity{account}
 	name := channel.Name()
 	cache := node.Cache()
 	for _, d := range deltas {
+		d, err := c.compressDelta(compression, d)
+		if err != nil {
+			return err
+		}
 		data, err := proto.Marshal(d)
 		if err != nil {
 			return err
@@ -189,23 +357,89 @@ func (c *spaceClient) Amend(node bcgo.Node, listener bcgo.MiningListener, channe
 			return err
 		}
 
-		if _, err := bcgo.WriteRecord(name, cache, record); err != nil {
+		reference, err := bcgo.WriteRecord(name, cache, record)
+		if err != nil {
 			return err
 		}
+		c.written(channel, reference)
 	}
 
 	// Mine file channel
-	if _, _, err := bcgo.Mine(node, channel, spacego.THRESHOLD_CUSTOMER, listener); err != nil {
+	if err := c.mine(node, channel, listener); err != nil {
 		return err
 	}
+	return nil
+}
 
-	if n := node.Network(); n != nil && !reflect.ValueOf(n).IsNil() {
-		// Push update to peers
-		if err := channel.Push(node.Cache(), n); err != nil {
-			log.Println(err)
+// amendFile adds the given deltas to the given channel holding the deltas of the file with the given meta ID.
+func (c *spaceClient) amendFile(node bcgo.Node, listener bcgo.MiningListener, metaId []byte, channel bcgo.Channel, deltas ...*spacego.Delta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	metas := c.openMetas(node)
+	f, err := readFile(metas, node.Cache(), node.Network(), node.Account(), metaId)
+	if err != nil {
+		return err
+	}
+	if f == nil || f.deleted() {
+		// File may have been shared by another alias
+		s, err := c.sharedFile(node, metas, metaId)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return c.errFileNotFound(node, metaId)
 		}
+		f = s.file
 	}
-	return nil
+	return c.amend(node, listener, f, channel, deltas...)
+}
+
+// UpdateMeta of the file with the given meta ID to the given name and MIME type, an empty string leaves that field unchanged.
+// Metadata records are immutable so a new version superseding the original is written instead.
+// MIME types marking files in the trash cannot be set, use Delete instead.
+func (c *spaceClient) UpdateMeta(node bcgo.Node, listener bcgo.MiningListener, metaId []byte, name, mime string) error {
+	if err := reservedType(mime); err != nil {
+		return err
+	}
+	account := node.Account()
+	alias := account.Alias()
+	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	c.refresh(node, metas)
+	f, err := readFile(metas, node.Cache(), node.Network(), account, metaId)
+	if err != nil {
+		return err
+	}
+	if f == nil || f.deleted() {
+		return c.errNotOwned(node, metaId)
+	}
+	meta := proto.Clone(f.latest().meta).(*spacego.Meta)
+	if name != "" {
+		meta.Name = name
+	}
+	if mime != "" {
+		meta.Type = mime
+	}
+	if err := c.writeMeta(node, metas, f, meta); err != nil {
+		return err
+	}
+	return c.mine(node, metas, listener)
+}
+
+// writeMeta writes a new version of the given file's meta data, readable by all aliases with access to the file.
+func (c *spaceClient) writeMeta(node bcgo.Node, metas bcgo.Channel, f *file, meta *spacego.Meta) error {
+	access, err := c.identities(node, f.access())
+	if err != nil {
+		return err
+	}
+	data, err := f.marshal(meta)
+	if err != nil {
+		return err
+	}
+	_, err = node.Write(bcgo.Timestamp(), metas, access, []*bcgo.Reference{f.reference(metas.Name())}, data)
+	return err
 }
 
 // MetaForHash owned by key with given meta ID
@@ -214,12 +448,23 @@ func (c *spaceClient) MetaForHash(node bcgo.Node, recordHash []byte, callback sp
 	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
 		return spacego.OpenMetaChannel(alias)
 	})
-	if err := metas.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
+	c.refresh(node, metas)
+	f, err := readFile(metas, node.Cache(), node.Network(), node.Account(), recordHash)
+	if err != nil {
+		return err
 	}
-	return spacego.ReadMeta(metas, node.Cache(), node.Network(), node.Account(), recordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
-		return callback(entry, meta)
-	})
+	if f != nil && !f.deleted() {
+		return callback(f.entry(), f.latest().meta)
+	}
+	// File may have been shared by another alias
+	s, err := c.sharedFile(node, metas, recordHash)
+	if err != nil {
+		return err
+	}
+	if s != nil {
+		return callback(s.entry, s.meta)
+	}
+	return c.errFileNotFound(node, recordHash)
 }
 
 // AllMetas lists files owned by key
@@ -228,67 +473,168 @@ func (c *spaceClient) AllMetas(node bcgo.Node, callback spacego.MetaCallback) er
 	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
 		return spacego.OpenMetaChannel(alias)
 	})
-	if err := metas.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
+	c.refresh(node, metas)
+	files, err := readFiles(metas, node.Cache(), node.Network(), node.Account())
+	if err != nil {
+		return err
 	}
-	return spacego.ReadMeta(metas, node.Cache(), node.Network(), node.Account(), nil, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
-		return callback(entry, meta)
-	})
+	for _, f := range files {
+		if f.deleted() {
+			continue
+		}
+		if err := callback(f.entry(), f.latest().meta); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ReadFile with the given meta ID.
 func (c *spaceClient) ReadFile(node bcgo.Node, metaId []byte) (io.Reader, error) {
-	// TODO read from cache if file exists
-	mId := base64.RawURLEncoding.EncodeToString(metaId)
-	deltas := node.OpenChannel(spacego.DeltaChannelName(mId), func() bcgo.Channel {
-		return spacego.OpenDeltaChannel(mId)
-	})
-	if err := deltas.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
+	if data, ok := c.cachedContent(node, metaId); ok {
+		return bytes.NewReader(data), nil
+	}
+	data, deltas, err := c.readContent(node, metaId)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.putContent(node, metaId, deltas, data); err != nil {
+		c.logger.Warn("Could not cache content", "error", err)
+	}
+	return bytes.NewReader(data), nil
+}
+
+// OpenFile with the given meta ID.
+// Unlike ReadFile large files are not held in memory, instead the layout of the file is computed by reading each delta once, and the bytes of the ranges being read are loaded from the blocks holding their deltas.
+func (c *spaceClient) OpenFile(node bcgo.Node, metaId []byte) (io.ReadSeekCloser, error) {
+	return c.open(node, metaId)
+}
+
+// ReadFileRange with the given meta ID, returning at most length bytes from the given offset.
+// Every delta is read to compute the layout of the file, but only the bytes within the range are held in memory.
+// Returns an error if the offset is beyond the end of the file.
+func (c *spaceClient) ReadFileRange(node bcgo.Node, metaId []byte, offset, length int64) (io.Reader, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("Invalid range: %d-%d", offset, length)
+	}
+	r, err := c.open(node, metaId)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	size := r.Size()
+	if offset > size {
+		return nil, fmt.Errorf("Invalid range: offset %d is beyond size %d", offset, size)
 	}
+	if remaining := size - offset; length > remaining {
+		length = remaining
+	}
+	buffer := make([]byte, length)
+	if _, err := io.ReadFull(io.NewSectionReader(r, offset, length), buffer); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buffer), nil
+}
+
+// ReadFileAt with the given meta ID, returning the content as it was at the given timestamp.
+func (c *spaceClient) ReadFileAt(node bcgo.Node, metaId []byte, timestamp uint64) (io.Reader, error) {
 	buffer := []byte{}
-	if err := spacego.IterateDeltas(node, deltas, func(entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+	if _, err := c.iterateDeltas(node, metaId, func(channel bcgo.Channel, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		if entry.Record.Timestamp > timestamp {
+			return errStopped
+		}
 		buffer = spacego.ApplyDelta(delta, buffer)
 		return nil
-	}); err != nil {
+	}); err != nil && err != errStopped {
+		return nil, err
+	}
+	return bytes.NewReader(buffer), nil
+}
+
+// ReadFileVersion with the given meta ID, returning the content as it was after the delta with the given record hash.
+func (c *spaceClient) ReadFileVersion(node bcgo.Node, metaId, recordHash []byte) (io.Reader, error) {
+	buffer, err := c.readVersion(node, metaId, recordHash)
+	if err != nil {
 		return nil, err
 	}
 	return bytes.NewReader(buffer), nil
 }
 
+// HistoryCallback is triggered with the hash of the block containing each delta of a file.
+type HistoryCallback func([]byte, *bcgo.BlockEntry, *spacego.Delta) error
+
+// History of the file with the given meta ID, triggering the callback with each delta in the order applied.
+func (c *spaceClient) History(node bcgo.Node, metaId []byte, callback HistoryCallback) error {
+	blocks := make(map[string][]byte)
+	indexed := make(map[string]bool)
+	_, err := c.iterateDeltas(node, metaId, func(channel bcgo.Channel, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		if name := channel.Name(); !indexed[name] {
+			indexed[name] = true
+			if err := bcgo.Iterate(name, channel.Head(), nil, node.Cache(), node.Network(), func(hash []byte, block *bcgo.Block) error {
+				for _, e := range block.Entry {
+					blocks[string(e.RecordHash)] = hash
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return callback(blocks[string(entry.RecordHash)], entry, delta)
+	})
+	return err
+}
+
 // WriteFile with the given meta ID.
 func (c *spaceClient) WriteFile(node bcgo.Node, listener bcgo.MiningListener, metaId []byte) (io.WriteCloser, error) {
-	mId := base64.RawURLEncoding.EncodeToString(metaId)
-	deltas := node.OpenChannel(spacego.DeltaChannelName(mId), func() bcgo.Channel {
-		return spacego.OpenDeltaChannel(mId)
-	})
-	if err := deltas.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
-	}
 	// Read current file into a old buffer
 	old := []byte{}
-	if err := spacego.IterateDeltas(node, deltas, func(entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+	deltas, err := c.iterateDeltas(node, metaId, func(channel bcgo.Channel, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
 		old = spacego.ApplyDelta(delta, old)
 		return nil
-	}); err != nil {
+	})
+	if err != nil {
 		return nil, err
 	}
 	var new bytes.Buffer
 	return spacego.NewCloser(&new, func() error {
-		return c.Amend(node, listener, deltas, spacego.Difference(old, new.Bytes())...)
+		return c.amendFile(node, listener, metaId, deltas, spacego.Difference(old, new.Bytes())...)
 	}), nil
 }
 
+// Revert the file with the given meta ID to the version after the delta with the given record hash.
+// The reversion is recorded as a new amendment so the history of the file is preserved.
+func (c *spaceClient) Revert(node bcgo.Node, listener bcgo.MiningListener, metaId, recordHash []byte) error {
+	old, err := c.readVersion(node, metaId, recordHash)
+	if err != nil {
+		return err
+	}
+	current := []byte{}
+	deltas, err := c.iterateDeltas(node, metaId, func(channel bcgo.Channel, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		current = spacego.ApplyDelta(delta, current)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	difference := spacego.Difference(current, old)
+	if len(difference) == 0 {
+		// Nothing to revert
+		return nil
+	}
+	return c.amendFile(node, listener, metaId, deltas, difference...)
+}
+
 // WatchFile triggers the given callback whenever the file with given meta ID updates.
+// Use WatchFileChanges to receive the changes themselves.
 func (c *spaceClient) WatchFile(ctx context.Context, node bcgo.Node, metaId []byte, callback func()) {
-	initial := time.Second
-	limit := time.Hour
-	duration := initial
-	ticker := time.NewTicker(duration)
-	mId := base64.RawURLEncoding.EncodeToString(metaId)
-	deltas := node.OpenChannel(spacego.DeltaChannelName(mId), func() bcgo.Channel {
-		return spacego.OpenDeltaChannel(mId)
-	})
+	deltas, err := c.deltaChannel(node, metaId)
+	if err != nil {
+		c.logger.Warn("Could not find deltas", "error", err)
+		mId := base64.RawURLEncoding.EncodeToString(metaId)
+		deltas = node.OpenChannel(spacego.DeltaChannelName(mId), func() bcgo.Channel {
+			return spacego.OpenDeltaChannel(mId)
+		})
+	}
 	deltas.AddTrigger(func() {
 		if ctx.Err() != nil {
 			// Context was already cancelled
@@ -296,20 +642,131 @@ func (c *spaceClient) WatchFile(ctx context.Context, node bcgo.Node, metaId []by
 		}
 		go callback()
 	})
-	// TODO replace polling with mechanism to register with provider to listen for remote updates
+	c.watch(ctx, node, deltas)
+}
+
+// WatchMetas triggers the given callback with each file added to, or updated in, the account's meta channel, including by other devices.
+// Files moved to the trash are not reported.
+func (c *spaceClient) WatchMetas(ctx context.Context, node bcgo.Node, callback spacego.MetaCallback) {
+	metas := c.openMetas(node)
+	var lock sync.Mutex
+	// Record the latest version of each file so only changes are reported
+	latest := make(map[string][]byte)
+	files, err := readFiles(metas, node.Cache(), node.Network(), node.Account())
+	if err != nil {
+		c.logger.Error("Could not read files", "error", err)
+	}
+	for _, f := range files {
+		latest[string(f.id)] = f.latest().entry.RecordHash
+	}
+	metas.AddTrigger(func() {
+		if ctx.Err() != nil {
+			// Context was already cancelled
+			return
+		}
+		lock.Lock()
+		files, err := readFiles(metas, node.Cache(), node.Network(), node.Account())
+		var changed []*file
+		// Report oldest changes first
+		for i := len(files) - 1; i >= 0; i-- {
+			f := files[i]
+			hash := f.latest().entry.RecordHash
+			if bytes.Equal(latest[string(f.id)], hash) {
+				continue
+			}
+			latest[string(f.id)] = hash
+			if !f.deleted() {
+				changed = append(changed, f)
+			}
+		}
+		lock.Unlock()
+		if err != nil {
+			c.logger.Error("Could not read files", "error", err)
+			return
+		}
+		// Callback is triggered without holding the lock as it may refresh the channel
+		for _, f := range changed {
+			if err := callback(f.entry(), f.latest().meta); err != nil {
+				c.logger.Error("Callback failed", "error", err)
+			}
+		}
+	})
+	c.watch(ctx, node, metas)
+}
+
+// watch refreshes the given channel, triggering its triggers, whenever it updates until the context is done.
+// The channel is polled with exponential backoff, unless updates are pushed by a peer through the client's notifier.
+// While no peer is available subscribing is retried with the same backoff as polling, so peers are not dialled on every poll.
+func (c *spaceClient) watch(ctx context.Context, node bcgo.Node, channel bcgo.Channel) {
+	initial := time.Second
+	limit := time.Hour
+	duration := initial
+	ticker := time.NewTicker(duration)
 	go func() {
 		defer ticker.Stop()
+		var subscription <-chan error
+		// Notified heads are handled by this goroutine so the channel is only refreshed from here
+		updates := make(chan []byte, 1)
+		var retry time.Time
+		retryDelay := initial
+		subscribe := func() {
+			if c.notifier == nil || time.Now().Before(retry) {
+				return
+			}
+			s, err := c.notifier.Subscribe(ctx, channel.Name(), func(head []byte) {
+				select {
+				case <-updates:
+					// Drop stale head
+				default:
+				}
+				updates <- head
+			})
+			if err != nil {
+				c.logger.Warn("Subscription failed", "channel", channel.Name(), "error", err)
+				retry = time.Now().Add(retryDelay)
+				retryDelay *= 2
+				if retryDelay > limit {
+					retryDelay = limit
+				}
+				return
+			}
+			subscription = s
+			retryDelay = initial
+			// Catch up with any updates made before subscribing
+			c.refresh(node, channel)
+		}
+		subscribe()
 		var errors int
 		for {
 			select {
 			case <-ctx.Done():
 				return
+			case head := <-updates:
+				if !bytes.Equal(head, channel.Head()) {
+					c.refresh(node, channel)
+				}
+			case err := <-subscription:
+				// Subscription ended, fallback to polling
+				if ctx.Err() != nil {
+					return
+				}
+				c.logger.Warn("Subscription ended", "channel", channel.Name(), "error", err)
+				subscription = nil
+				duration = initial
+				errors = 0
+				ticker.Stop()
+				ticker = time.NewTicker(duration)
 			case <-ticker.C:
-				head := deltas.Head()
-				if err := deltas.Refresh(node.Cache(), node.Network()); err != nil {
-					log.Println(err)
+				if subscription == nil {
+					subscribe()
+				}
+				if subscription != nil {
+					// Updates are pushed
+					continue
 				}
-				if bytes.Equal(head, deltas.Head()) {
+				head := channel.Head()
+				c.refresh(node, channel)
+				if bytes.Equal(head, channel.Head()) {
 					// No change
 					errors++
 					if errors > 3 {
@@ -339,106 +796,419 @@ func (c *spaceClient) SearchMeta(node bcgo.Node, filter spacego.MetaFilter, call
 	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
 		return spacego.OpenMetaChannel(alias)
 	})
-	if err := metas.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
+	c.refresh(node, metas)
+	files, err := readFiles(metas, node.Cache(), node.Network(), account)
+	if err != nil {
+		return err
 	}
-	if err := spacego.ReadMeta(metas, node.Cache(), node.Network(), account, nil, func(metaEntry *bcgo.BlockEntry, meta *spacego.Meta) error {
+	for _, f := range files {
+		if f.deleted() {
+			continue
+		}
+		meta := f.latest().meta
 		if filter != nil && !filter.Filter(meta) {
 			// Meta doesn't pass filter
-			return nil
+			continue
+		}
+		if err := callback(f.entry(), meta); err != nil {
+			return err
 		}
-		return callback(metaEntry, meta)
-	}); err != nil {
-		return err
 	}
 	return nil
 }
 
-// SearchTag searches files by tag
+// SEARCH_TAG_WORKERS is the number of tag channels read concurrently by SearchTag.
+const SEARCH_TAG_WORKERS = 16
+
+// SearchTag searches files by tag, triggering the callback once for each file with a tag passing the filter, in the same order as SearchMeta.
+// Refreshing a tag channel is dominated by network latency so the tag channels of several files are read concurrently,
+// workers take turns opening channels and using the cache, and only wait on peers at the same time.
 func (c *spaceClient) SearchTag(node bcgo.Node, filter spacego.TagFilter, callback spacego.MetaCallback) error {
-	return c.SearchMeta(node, nil, func(metaEntry *bcgo.BlockEntry, meta *spacego.Meta) error {
-		metaId := base64.RawURLEncoding.EncodeToString(metaEntry.RecordHash)
-		tags := node.OpenChannel(spacego.TagChannelName(metaId), func() bcgo.Channel {
-			return spacego.OpenTagChannel(metaId)
+	return c.searchTags(node, false, filter, func(entry *bcgo.BlockEntry, meta *spacego.Meta, tag *spacego.Tag) error {
+		return callback(entry, meta)
+	})
+}
+
+// ContentHashes triggers the callback with the hash of the content of each file added by AddAll with a FileSpec.Hash, in the same order as SearchMeta.
+func (c *spaceClient) ContentHashes(node bcgo.Node, callback ContentHashCallback) error {
+	return c.searchTags(node, true, nil, func(entry *bcgo.BlockEntry, meta *spacego.Meta, tag *spacego.Tag) error {
+		return callback(entry, meta, strings.TrimPrefix(tag.Value, CONTENT_HASH_TAG_PREFIX))
+	})
+}
+
+// searchTags triggers the callback with each file and the first of its tags passing the filter, reading only hidden tags if hidden is true, and only visible tags otherwise.
+func (c *spaceClient) searchTags(node bcgo.Node, hidden bool, filter spacego.TagFilter, callback func(*bcgo.BlockEntry, *spacego.Meta, *spacego.Tag) error) error {
+	type result struct {
+		entry *bcgo.BlockEntry
+		meta  *spacego.Meta
+		tag   *spacego.Tag
+		err   error
+		done  chan struct{}
+	}
+	var results []*result
+	if err := c.SearchMeta(node, nil, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		results = append(results, &result{
+			entry: entry,
+			meta:  meta,
+			done:  make(chan struct{}),
 		})
-		if err := tags.Refresh(node.Cache(), node.Network()); err != nil {
-			log.Println(err)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Read tags with a bounded pool of workers until all are read, or the search ends early
+	stop := make(chan struct{})
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	go func() {
+		defer close(indices)
+		for i := range results {
+			select {
+			case indices <- i:
+			case <-stop:
+				return
+			}
 		}
-		return spacego.ReadTag(tags, node.Cache(), node.Network(), node.Account(), nil, func(tagEntry *bcgo.BlockEntry, tag *spacego.Tag) error {
-			if filter != nil && !filter.Filter(tag) {
-				// Tag doesn't pass filter
-				return nil
+	}()
+	workers := SEARCH_TAG_WORKERS
+	if workers > len(results) {
+		workers = len(results)
+	}
+	var lock sync.Mutex
+	serial := &serialNode{
+		Node: node,
+		lock: &lock,
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				r := results[i]
+				lock.Lock()
+				if err := nodeContext(node).Err(); err != nil {
+					r.err = err
+				} else if err := c.readTags(serial, r.entry.RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+					if hiddenTag(tag.Value) != hidden {
+						return nil
+					}
+					if filter != nil && !filter.Filter(tag) {
+						// Tag doesn't pass filter
+						return nil
+					}
+					// File matches, remaining tags need not be read
+					r.tag = tag
+					return errStopped
+				}); err != errStopped {
+					r.err = err
+				}
+				lock.Unlock()
+				close(r.done)
 			}
-			return callback(metaEntry, meta)
-		})
-	})
+		}()
+	}
+
+	// Trigger callback in order as each file's tags are read
+	for _, r := range results {
+		<-r.done
+		if r.err != nil {
+			return r.err
+		}
+		if r.tag == nil {
+			continue
+		}
+		if err := callback(r.entry, r.meta, r.tag); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // AddTag adds the given tag for the file with the given meta ID
 func (c *spaceClient) AddTag(node bcgo.Node, listener bcgo.MiningListener, metaId []byte, tag []string) ([]*bcgo.Reference, error) {
+	for _, t := range tag {
+		if hiddenTag(t) {
+			return nil, fmt.Errorf("Reserved tag: %s", t)
+		}
+	}
+	return c.addTag(node, listener, metaId, tag)
+}
+
+// addTag adds the given tag for the file with the given meta ID, including hidden tags.
+func (c *spaceClient) addTag(node bcgo.Node, listener bcgo.MiningListener, metaId []byte, tag []string) ([]*bcgo.Reference, error) {
 	account := node.Account()
 	alias := account.Alias()
 	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
 		return spacego.OpenMetaChannel(alias)
 	})
-	if err := metas.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
-	}
+	c.refresh(node, metas)
 	mId := base64.RawURLEncoding.EncodeToString(metaId)
 	tags := node.OpenChannel(spacego.TagChannelName(mId), func() bcgo.Channel {
 		return spacego.OpenTagChannel(mId)
 	})
-	if err := tags.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
+	c.refresh(node, tags)
+	f, err := readFile(metas, node.Cache(), node.Network(), account, metaId)
+	if err != nil {
+		return nil, err
 	}
-	var references []*bcgo.Reference
-	if err := spacego.ReadMeta(metas, node.Cache(), node.Network(), account, metaId, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
-		for _, t := range tag {
-			tag := spacego.Tag{
-				Value: t,
-			}
-			data, err := proto.Marshal(&tag)
-			if err != nil {
-				return err
-			}
-			references := []*bcgo.Reference{&bcgo.Reference{
-				Timestamp:   entry.Record.Timestamp,
-				ChannelName: metas.Name(),
-				RecordHash:  metaId,
-			}}
-			reference, err := node.Write(bcgo.Timestamp(), tags, []bcgo.Identity{account}, references, data)
-			if err != nil {
-				return err
-			}
-			references = append(references, reference)
-			if _, _, err := bcgo.Mine(node, tags, spacego.THRESHOLD_CUSTOMER, listener); err != nil {
-				return err
-			}
-		}
-		return nil
-	}); err != nil {
+	if f == nil || f.deleted() {
+		return nil, c.errNotOwned(node, metaId)
+	}
+	access, err := c.identities(node, f.access())
+	if err != nil {
 		return nil, err
 	}
-	return references, nil
+	var (
+		references []*bcgo.Reference
+		failure    error
+	)
+	for _, t := range tag {
+		tag := spacego.Tag{
+			Value: t,
+		}
+		data, err := proto.Marshal(&tag)
+		if err != nil {
+			return nil, err
+		}
+		reference, err := node.Write(bcgo.Timestamp(), tags, access, []*bcgo.Reference{f.reference(metas.Name())}, data)
+		if err != nil {
+			return nil, err
+		}
+		references = append(references, reference)
+		if err := pushed(c.mine(node, tags, listener), &failure); err != nil {
+			return nil, err
+		}
+	}
+	return references, failure
 }
 
-// AllTagsForHash lists all tags for the file with the given meta ID
+// AllTagsForHash lists all tags for the file with the given meta ID, except hidden tags such as content hashes.
 func (c *spaceClient) AllTagsForHash(node bcgo.Node, metaId []byte, callback spacego.TagCallback) error {
+	return c.readTags(node, metaId, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+		if hiddenTag(tag.Value) {
+			return nil
+		}
+		return callback(entry, tag)
+	})
+}
+
+// readTags triggers the callback with each tag of the file with the given meta ID which has not been removed, including hidden tags.
+func (c *spaceClient) readTags(node bcgo.Node, metaId []byte, callback spacego.TagCallback) error {
 	mId := base64.RawURLEncoding.EncodeToString(metaId)
 	tags := node.OpenChannel(spacego.TagChannelName(mId), func() bcgo.Channel {
 		return spacego.OpenTagChannel(mId)
 	})
-	if err := tags.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
+	c.refresh(node, tags)
+	type pair struct {
+		entry *bcgo.BlockEntry
+		tag   *spacego.Tag
 	}
-	return spacego.ReadTag(tags, node.Cache(), node.Network(), node.Account(), nil, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+	var pairs []*pair
+	removed := newTagRemovals()
+	if err := spacego.ReadTag(tags, node.Cache(), node.Network(), node.Account(), nil, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+		if removed.add(tags, entry, tag) {
+			return nil
+		}
 		for _, reference := range entry.Record.Reference {
 			if bytes.Equal(metaId, reference.RecordHash) {
-				callback(entry, tag)
+				pairs = append(pairs, &pair{entry, tag})
 			}
 		}
 		return nil
+	}); err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		if removed.removed(p.entry, p.tag) {
+			continue
+		}
+		if err := callback(p.entry, p.tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchTags triggers the given callback with each tag added to the file with the given meta ID, including by other aliases with access to the file.
+// Tags which are later removed are not reported again, and hidden tags are not reported.
+func (c *spaceClient) WatchTags(ctx context.Context, node bcgo.Node, metaId []byte, callback spacego.TagCallback) {
+	mId := base64.RawURLEncoding.EncodeToString(metaId)
+	tags := node.OpenChannel(spacego.TagChannelName(mId), func() bcgo.Channel {
+		return spacego.OpenTagChannel(mId)
 	})
+	var lock sync.Mutex
+	// Record the tags already seen so only new tags are reported
+	seen := make(map[string]bool)
+	if err := c.AllTagsForHash(node, metaId, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+		seen[string(entry.RecordHash)] = true
+		return nil
+	}); err != nil {
+		c.logger.Error("Could not read tags", "error", err)
+	}
+	tags.AddTrigger(func() {
+		if ctx.Err() != nil {
+			// Context was already cancelled
+			return
+		}
+		type pair struct {
+			entry *bcgo.BlockEntry
+			tag   *spacego.Tag
+		}
+		var added []*pair
+		removed := newTagRemovals()
+		lock.Lock()
+		err := spacego.ReadTag(tags, node.Cache(), node.Network(), node.Account(), nil, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+			if removed.add(tags, entry, tag) {
+				return nil
+			}
+			if seen[string(entry.RecordHash)] || hiddenTag(tag.Value) {
+				return nil
+			}
+			for _, reference := range entry.Record.Reference {
+				if bytes.Equal(metaId, reference.RecordHash) {
+					seen[string(entry.RecordHash)] = true
+					added = append(added, &pair{entry, tag})
+					break
+				}
+			}
+			return nil
+		})
+		lock.Unlock()
+		if err != nil {
+			c.logger.Error("Could not read tags", "error", err)
+			return
+		}
+		// Tags are read newest first, report oldest first
+		for i := len(added) - 1; i >= 0; i-- {
+			if removed.removed(added[i].entry, added[i].tag) {
+				continue
+			}
+			if err := callback(added[i].entry, added[i].tag); err != nil {
+				c.logger.Error("Callback failed", "error", err)
+			}
+		}
+	})
+	c.watch(ctx, node, tags)
+}
+
+// RemoveTag from the file with the given meta ID.
+// Tag records are immutable so a removal marker referencing each matching tag record is written instead,
+// readable by every alias with access to the file so copies of the tag written by Share are removed too.
+func (c *spaceClient) RemoveTag(node bcgo.Node, listener bcgo.MiningListener, metaId []byte, tag []string) ([]*bcgo.Reference, error) {
+	account := node.Account()
+	alias := account.Alias()
+	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	c.refresh(node, metas)
+	f, err := readFile(metas, node.Cache(), node.Network(), account, metaId)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil || f.deleted() {
+		return nil, c.errNotOwned(node, metaId)
+	}
+	access, err := c.identities(node, f.access())
+	if err != nil {
+		return nil, err
+	}
+	mId := base64.RawURLEncoding.EncodeToString(metaId)
+	tags := node.OpenChannel(spacego.TagChannelName(mId), func() bcgo.Channel {
+		return spacego.OpenTagChannel(mId)
+	})
+	matches := make(map[string][]*bcgo.Reference)
+	if err := c.AllTagsForHash(node, metaId, func(entry *bcgo.BlockEntry, t *spacego.Tag) error {
+		matches[t.Value] = append(matches[t.Value], &bcgo.Reference{
+			Timestamp:   entry.Record.Timestamp,
+			ChannelName: tags.Name(),
+			RecordHash:  entry.RecordHash,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	var references []*bcgo.Reference
+	for _, t := range tag {
+		if len(matches[t]) == 0 {
+			continue
+		}
+		data, err := proto.Marshal(&spacego.Tag{
+			Value: t,
+		})
+		if err != nil {
+			return nil, err
+		}
+		reference, err := node.Write(bcgo.Timestamp(), tags, access, append([]*bcgo.Reference{f.reference(metas.Name())}, matches[t]...), data)
+		if err != nil {
+			return nil, err
+		}
+		references = append(references, reference)
+		delete(matches, t)
+	}
+	if len(references) == 0 {
+		// Nothing to remove
+		return nil, nil
+	}
+	var failure error
+	if err := pushed(c.mine(node, tags, listener), &failure); err != nil {
+		return nil, err
+	}
+	return references, failure
+}
+
+// tagRemovals records the tags removed by the removal markers read from a tag channel.
+//
+// A marker removes the tag records it references. Share writes a copy of each
+// tag readable by the recipients which the owner cannot read, so a marker also
+// removes every tag with the same value written before it, and removals made
+// by the owner reach the recipients' copies.
+type tagRemovals struct {
+	hashes map[string]bool
+	// values holds the timestamp of the latest marker removing each value.
+	values map[string]uint64
+}
+
+func newTagRemovals() *tagRemovals {
+	return &tagRemovals{
+		hashes: make(map[string]bool),
+		values: make(map[string]uint64),
+	}
+}
+
+// add records the tags removed by the given entry, returning false if the entry is not a removal marker.
+func (r *tagRemovals) add(tags bcgo.Channel, entry *bcgo.BlockEntry, tag *spacego.Tag) bool {
+	markers := removedTags(tags, entry)
+	if len(markers) == 0 {
+		return false
+	}
+	for _, m := range markers {
+		r.hashes[string(m)] = true
+	}
+	if t := entry.Record.Timestamp; t > r.values[tag.Value] {
+		r.values[tag.Value] = t
+	}
+	return true
+}
+
+// removed returns true if the given tag was removed by a marker.
+func (r *tagRemovals) removed(entry *bcgo.BlockEntry, tag *spacego.Tag) bool {
+	return r.hashes[string(entry.RecordHash)] || entry.Record.Timestamp < r.values[tag.Value]
+}
+
+// removedTags returns the hashes of the tag records removed by the given entry, or nil if the entry is not a removal marker.
+func removedTags(tags bcgo.Channel, entry *bcgo.BlockEntry) [][]byte {
+	var hashes [][]byte
+	for _, r := range entry.Record.Reference {
+		if r.ChannelName == tags.Name() {
+			hashes = append(hashes, r.RecordHash)
+		}
+	}
+	return hashes
 }
 
 // Registration triggers the given callback for the most recent registration with the given merchant.
@@ -447,12 +1217,14 @@ func (c *spaceClient) Registration(merchant string, callback financego.Registrat
 	if err != nil {
 		return err
 	}
+	return c.merchantRegistration(node, merchant, callback)
+}
+
+func (c *spaceClient) merchantRegistration(node bcgo.Node, merchant string, callback financego.RegistrationCallback) error {
 	registrations := node.OpenChannel(spacego.SPACE_REGISTRATION, func() bcgo.Channel {
 		return spacego.OpenRegistrationChannel()
 	})
-	if err := registrations.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
-	}
+	c.refresh(node, registrations)
 	return financego.RegistrationAsync(registrations, node.Cache(), node.Network(), node.Account(), merchant, node.Account().Alias(), callback)
 }
 
@@ -462,11 +1234,177 @@ func (c *spaceClient) Subscription(merchant string, callback financego.Subscript
 	if err != nil {
 		return err
 	}
+	return c.merchantSubscription(node, merchant, callback)
+}
+
+func (c *spaceClient) merchantSubscription(node bcgo.Node, merchant string, callback financego.SubscriptionCallback) error {
 	subscriptions := node.OpenChannel(spacego.SPACE_SUBSCRIPTION, func() bcgo.Channel {
 		return spacego.OpenSubscriptionChannel()
 	})
-	if err := subscriptions.Refresh(node.Cache(), node.Network()); err != nil {
-		log.Println(err)
-	}
+	c.refresh(node, subscriptions)
 	return financego.SubscriptionAsync(subscriptions, node.Cache(), node.Network(), node.Account(), merchant, node.Account().Alias(), "", "", callback)
 }
+
+// iterateDeltas triggers the given callback for each delta of the file with the given meta ID, in the order they were written, with the bytes it inserts decompressed.
+// Files which have been re-keyed are followed into the channel they were forwarded to, and the channel currently holding the file is returned.
+func (c *spaceClient) iterateDeltas(node bcgo.Node, metaId []byte, callback func(bcgo.Channel, *bcgo.BlockEntry, *spacego.Delta) error) (bcgo.Channel, error) {
+	return c.followDeltas(node, metaId, func(deltas bcgo.Channel, compression string) (string, error) {
+		var next string
+		if err := spacego.IterateDeltas(node, deltas, func(entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+			if err := c.decompressDelta(compression, delta); err != nil {
+				return err
+			}
+			if err := callback(deltas, entry, delta); err != nil {
+				return err
+			}
+			if next = forward(deltas, entry); next != "" {
+				// Remaining deltas in this channel are superseded
+				return errForwarded
+			}
+			return nil
+		}); err != nil && err != errForwarded {
+			return "", err
+		}
+		return next, nil
+	})
+}
+
+// followDeltas triggers the given function with each delta channel holding the file with the given meta ID, and the compression of the file, until no forward is returned.
+// Returns an error wrapping ErrFileNotFound if the file does not exist or is in the trash.
+func (c *spaceClient) followDeltas(node bcgo.Node, metaId []byte, iterate func(bcgo.Channel, string) (string, error)) (bcgo.Channel, error) {
+	mId := base64.RawURLEncoding.EncodeToString(metaId)
+	deltas := node.OpenChannel(spacego.DeltaChannelName(mId), func() bcgo.Channel {
+		return spacego.OpenDeltaChannel(mId)
+	})
+	state, err := c.stateForHash(node, metaId)
+	if err != nil {
+		return nil, err
+	}
+	c.refresh(node, deltas)
+	visited := make(map[string]bool)
+	for {
+		visited[deltas.Name()] = true
+		next, err := iterate(deltas, state.compression)
+		if err != nil {
+			return nil, err
+		}
+		if next == "" || visited[next] {
+			return deltas, nil
+		}
+		id := strings.TrimPrefix(next, spacego.DeltaChannelName(""))
+		deltas = node.OpenChannel(next, func() bcgo.Channel {
+			return spacego.OpenDeltaChannel(id)
+		})
+		c.refresh(node, deltas)
+	}
+}
+
+// readContent returns the current content of the file with the given meta ID, and the channel currently holding its deltas.
+func (c *spaceClient) readContent(node bcgo.Node, metaId []byte) ([]byte, bcgo.Channel, error) {
+	buffer := []byte{}
+	deltas, err := c.iterateDeltas(node, metaId, func(channel bcgo.Channel, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		buffer = spacego.ApplyDelta(delta, buffer)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return buffer, deltas, nil
+}
+
+// readVersion returns the content of the file with the given meta ID after the delta with the given record hash.
+func (c *spaceClient) readVersion(node bcgo.Node, metaId, recordHash []byte) ([]byte, error) {
+	buffer := []byte{}
+	if _, err := c.iterateDeltas(node, metaId, func(channel bcgo.Channel, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		buffer = spacego.ApplyDelta(delta, buffer)
+		if bytes.Equal(entry.RecordHash, recordHash) {
+			return errStopped
+		}
+		return nil
+	}); err != errStopped {
+		if err == nil {
+			err = fmt.Errorf("%w: %s", ErrVersionNotFound, base64.RawURLEncoding.EncodeToString(recordHash))
+		}
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// deltaChannel returns the channel currently holding the deltas of the file with the given meta ID.
+func (c *spaceClient) deltaChannel(node bcgo.Node, metaId []byte) (bcgo.Channel, error) {
+	return c.iterateDeltas(node, metaId, func(bcgo.Channel, *bcgo.BlockEntry, *spacego.Delta) error {
+		return nil
+	})
+}
+
+var (
+	errForwarded = errors.New("Forwarded")
+	errStopped   = errors.New("Stopped")
+)
+
+// forward returns the name of the delta channel the given entry forwards the file to, or an empty string.
+func forward(channel bcgo.Channel, entry *bcgo.BlockEntry) string {
+	for _, r := range entry.Record.Reference {
+		if r.ChannelName != channel.Name() && strings.HasPrefix(r.ChannelName, spacego.DeltaChannelName("")) {
+			return r.ChannelName
+		}
+	}
+	return ""
+}
+
+// refresh updates the given channel from the node's cache and network.
+// Failures are logged and reported to the event hook, as the channel may still be read from the cache.
+func (c *spaceClient) refresh(node bcgo.Node, channel bcgo.Channel) {
+	if err := channel.Refresh(node.Cache(), node.Network()); err != nil {
+		if n := node.Network(); n != nil && !reflect.ValueOf(n).IsNil() {
+			c.unreachable.Store(channel.Name(), err)
+		}
+		c.logger.Warn("Refresh failed", "channel", channel.Name(), "error", err)
+		c.event(&Event{
+			Type:    EVENT_REFRESH_FAILED,
+			Channel: channel.Name(),
+			Error:   err,
+		})
+		return
+	}
+	c.unreachable.Delete(channel.Name())
+}
+
+// mine mines the given channel and pushes the new block to peers.
+// A failure to push is reported as ErrNetworkUnavailable, after the block has been mined.
+// The node's context is checked before and after mining but not observed while mining, as bcgo.Mine takes no context.
+// If the context ends while mining, the block is kept locally and pushed with the channel's next change.
+func (c *spaceClient) mine(node bcgo.Node, channel bcgo.Channel, listener bcgo.MiningListener) error {
+	ctx := nodeContext(node)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	hash, _, err := bcgo.Mine(node, channel, spacego.THRESHOLD_CUSTOMER, listener)
+	if err != nil {
+		return err
+	}
+	c.mined(channel, hash)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if n := node.Network(); n != nil && !reflect.ValueOf(n).IsNil() {
+		// Push update to peers
+		if err := channel.Push(node.Cache(), n); err != nil {
+			c.logger.Warn("Push failed", "channel", channel.Name(), "error", err)
+			c.event(&Event{
+				Type:    EVENT_PUSH_FAILED,
+				Channel: channel.Name(),
+				Hash:    hash,
+				Error:   err,
+			})
+			return pushError(channel, err)
+		}
+		c.logger.Debug("Pushed channel", "channel", channel.Name(), "head", base64.RawURLEncoding.EncodeToString(hash))
+		c.event(&Event{
+			Type:    EVENT_PUSHED,
+			Channel: channel.Name(),
+			Hash:    hash,
+		})
+	}
+	return nil
+}
@@ -17,18 +17,35 @@
 package spaceclientgo_test
 
 import (
+	"aletheiaware.com/aliasgo"
 	"aletheiaware.com/bcgo"
 	"aletheiaware.com/bcgo/account"
 	"aletheiaware.com/bcgo/cache"
 	"aletheiaware.com/bcgo/node"
 	"aletheiaware.com/spaceclientgo"
+	"aletheiaware.com/spaceclientgo/test"
 	"aletheiaware.com/spacego"
 	"aletheiaware.com/testinggo"
+	"bytes"
+	"compress/flate"
+	"context"
 	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
 	"io/ioutil"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func assertFile(t *testing.T, c spaceclientgo.SpaceClient, n bcgo.Node, metaId []byte, length int, content string) {
@@ -104,6 +121,142 @@ func TestClient_Add_and_ReadFile(t *testing.T) {
 	assertFile(t, client, node, ref.RecordHash, 7, "testing")
 }
 
+// countingListener counts the mining rounds started on each channel.
+type countingListener struct {
+	sync.Mutex
+	rounds map[string]int
+}
+
+func (l *countingListener) OnMiningStarted(channel bcgo.Channel, size uint64) {
+	l.Lock()
+	defer l.Unlock()
+	l.rounds[channel.Name()]++
+}
+
+func (l *countingListener) OnNewMaxOnes(bcgo.Channel, uint64, uint64) {}
+
+func (l *countingListener) OnMiningThresholdReached(bcgo.Channel, []byte, *bcgo.Block) {}
+
+func TestClient_AddAll_and_ReadFile(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(100)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	var files []spaceclientgo.FileSpec
+	for i := 0; i < 5; i++ {
+		files = append(files, spaceclientgo.FileSpec{
+			Name:   "test" + strconv.Itoa(i),
+			Mime:   "text/plain",
+			Reader: strings.NewReader("testing" + strconv.Itoa(i)),
+		})
+	}
+	files = append(files, spaceclientgo.FileSpec{
+		Name: "empty",
+		Mime: "text/plain",
+	})
+	listener := &countingListener{
+		rounds: make(map[string]int),
+	}
+	refs, err := client.AddAll(node, listener, files)
+	testinggo.AssertNoError(t, err)
+	assert.Equal(t, len(files), len(refs))
+
+	// Metas are mined together
+	assert.Equal(t, 1, listener.rounds[spacego.MetaChannelName(alias)])
+
+	var names []string
+	testinggo.AssertNoError(t, client.AllMetas(node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		names = append(names, meta.Name)
+		return nil
+	}))
+	assert.Equal(t, len(files), len(names))
+	for i := 0; i < 5; i++ {
+		assertFile(t, client, node, refs[i].RecordHash, 8, "testing"+strconv.Itoa(i))
+		var previews []*spacego.Preview
+		testinggo.AssertNoError(t, client.AllPreviewsForHash(node, refs[i].RecordHash, func(entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+			previews = append(previews, preview)
+			return nil
+		}))
+		assert.Equal(t, 1, len(previews))
+	}
+	assertFile(t, client, node, refs[5].RecordHash, 0, "")
+}
+
+func TestClient_AddAll_Push(t *testing.T) {
+	network := test.NewAcceptingNetwork()
+	network.Delay = 100 * time.Millisecond
+	node := makeNode(t, "Tester", cache.NewMemory(100), network)
+	client := spaceclientgo.NewSpaceClient()
+	var files []spaceclientgo.FileSpec
+	for i := 0; i < 4; i++ {
+		files = append(files, spaceclientgo.FileSpec{
+			Name:   "test" + strconv.Itoa(i),
+			Mime:   "application/octet-stream",
+			Reader: strings.NewReader("testing" + strconv.Itoa(i)),
+		})
+	}
+	refs, err := client.AddAll(node, nil, files)
+	testinggo.AssertNoError(t, err)
+	assert.Equal(t, len(files), len(refs))
+
+	// Content of several files is pushed at the same time
+	assert.True(t, network.MaxConcurrentBroadcasts() > 1)
+	for i := range files {
+		assertFile(t, client, node, refs[i].RecordHash, 8, "testing"+strconv.Itoa(i))
+	}
+}
+
+func TestClient_AddAll_Hash(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(100)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	refs, err := client.AddAll(node, nil, []spaceclientgo.FileSpec{
+		{
+			Name:   "hashed",
+			Mime:   "text/plain",
+			Reader: strings.NewReader("testing"),
+			Hash:   "cf80cd8aed482d5d1527d7dc72fceff84e6326592848447d2dc0b0e87dfc9a90",
+		},
+		{
+			Name: "empty",
+			Mime: "text/plain",
+			Hash: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+		{
+			Name:   "unhashed",
+			Mime:   "text/plain",
+			Reader: strings.NewReader("testing"),
+		},
+	})
+	testinggo.AssertNoError(t, err)
+	assert.Equal(t, 3, len(refs))
+	assertFile(t, client, node, refs[0].RecordHash, 7, "testing")
+
+	hashes := make(map[string]string)
+	testinggo.AssertNoError(t, client.ContentHashes(node, func(entry *bcgo.BlockEntry, meta *spacego.Meta, hash string) error {
+		hashes[meta.Name] = hash
+		return nil
+	}))
+	assert.Equal(t, map[string]string{
+		"hashed": "cf80cd8aed482d5d1527d7dc72fceff84e6326592848447d2dc0b0e87dfc9a90",
+		"empty":  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+	}, hashes)
+
+	// Hashes are hidden from tags
+	testinggo.AssertNoError(t, client.AllTagsForHash(node, refs[0].RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+		t.Fatalf("Unexpected tag: %s", tag.Value)
+		return nil
+	}))
+	testinggo.AssertNoError(t, client.SearchTag(node, nil, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		t.Fatalf("Unexpected file: %s", meta.Name)
+		return nil
+	}))
+	tag := spaceclientgo.CONTENT_HASH_TAG_PREFIX + "0000"
+	_, err = client.AddTag(node, nil, refs[2].RecordHash, []string{tag})
+	testinggo.AssertError(t, "Reserved tag: "+tag, err)
+}
+
 func TestClient_Amend_and_ReadFile(t *testing.T) {
 	alias := "Tester"
 	cache := cache.NewMemory(10)
@@ -157,6 +310,1016 @@ func TestClient_Write_and_ReadFile(t *testing.T) {
 	assertFile(t, client, node, ref.RecordHash, 26, "tasting=true\ntesting=false")
 }
 
+// flateCodec compresses content with flate, standing in for codecs given with WithCodec.
+type flateCodec struct{}
+
+func (flateCodec) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(writer, flate.DefaultCompression)
+}
+
+func (flateCodec) NewReader(reader io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(reader), nil
+}
+
+func TestClient_Compression(t *testing.T) {
+	for _, compression := range []string{spaceclientgo.COMPRESSION_GZIP, spaceclientgo.COMPRESSION_ZSTD, "flate"} {
+		t.Run(compression, func(t *testing.T) {
+			alias := "Tester"
+			cache := cache.NewMemory(10)
+			node := makeNode(t, alias, cache, nil)
+			client := spaceclientgo.NewSpaceClientWithOptions(
+				spaceclientgo.WithCompression(compression),
+				spaceclientgo.WithCodec("flate", flateCodec{}),
+			)
+			name := "test"
+			mime := "text/plain"
+			content := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10)
+			ref, err := client.Add(node, nil, name, mime, strings.NewReader(content))
+			testinggo.AssertNoError(t, err)
+
+			assertMeta(t, client, node, name, mime)
+			assertFile(t, client, node, ref.RecordHash, len(content), content)
+
+			// Stored content is compressed
+			metaId := base64.RawURLEncoding.EncodeToString(ref.RecordHash)
+			deltas := spacego.OpenDeltaChannel(metaId)
+			testinggo.AssertNoError(t, deltas.Load(node.Cache(), nil))
+			var stored int
+			testinggo.AssertNoError(t, spacego.IterateDeltas(node, deltas, func(entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+				stored += len(delta.Insert)
+				return nil
+			}))
+			assert.Equal(t, true, stored < len(content))
+
+			reader, err := client.ReadFileRange(node, ref.RecordHash, 4, 5)
+			testinggo.AssertNoError(t, err)
+			bytes, err := ioutil.ReadAll(reader)
+			testinggo.AssertNoError(t, err)
+			assert.Equal(t, "quick", string(bytes))
+
+			// Small changes write small deltas of the uncompressed content
+			edited := strings.Replace(content, "quick", "slow", 1)
+			w, err := client.WriteFile(node, nil, ref.RecordHash)
+			testinggo.AssertNoError(t, err)
+			_, err = w.Write([]byte(edited))
+			testinggo.AssertNoError(t, err)
+			testinggo.AssertNoError(t, w.Close())
+			assertFile(t, client, node, ref.RecordHash, len(edited), edited)
+			var last *spacego.Delta
+			testinggo.AssertNoError(t, deltas.Load(node.Cache(), nil))
+			testinggo.AssertNoError(t, spacego.IterateDeltas(node, deltas, func(entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+				last = delta
+				return nil
+			}))
+			assert.Equal(t, true, last.Delete < uint64(len(content)))
+			assert.Equal(t, true, last.Offset >= 4)
+
+			w, err = client.WriteFile(node, nil, ref.RecordHash)
+			testinggo.AssertNoError(t, err)
+			_, err = w.Write([]byte("testing"))
+			testinggo.AssertNoError(t, err)
+			testinggo.AssertNoError(t, w.Close())
+			assertFile(t, client, node, ref.RecordHash, 7, "testing")
+
+			// Compression survives metadata updates
+			testinggo.AssertNoError(t, client.UpdateMeta(node, nil, ref.RecordHash, "renamed", ""))
+			assertMeta(t, client, node, "renamed", mime)
+			assertFile(t, client, node, ref.RecordHash, 7, "testing")
+
+			// Compression is recorded in the meta data, so other clients decompress the content
+			other := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithCodec("flate", flateCodec{}))
+			assertFile(t, other, node, ref.RecordHash, 7, "testing")
+		})
+	}
+	t.Run("Unsupported", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithCompression("lz4"))
+		_, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertError(t, "Unsupported compression: lz4", err)
+		// No file was added
+		testinggo.AssertNoError(t, client.AllMetas(node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			t.Fatalf("Expected no files, got %s", meta.Name)
+			return nil
+		}))
+	})
+	t.Run("ReservedParameter", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClient()
+		mime := "text/plain" + spaceclientgo.COMPRESSION_PARAMETER + spaceclientgo.COMPRESSION_GZIP
+		_, err := client.Add(node, nil, "test", mime, strings.NewReader("testing"))
+		testinggo.AssertError(t, "Reserved MIME type parameter: "+mime, err)
+	})
+}
+
+func TestClient_Amend_Unresolved(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithCompression(spaceclientgo.COMPRESSION_GZIP))
+	// No file holds its deltas in the channel, so the delta is written uncompressed for the account
+	deltas := spacego.OpenDeltaChannel("unknown")
+	testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+		Insert: []byte("testing"),
+	}))
+	var inserts []string
+	testinggo.AssertNoError(t, spacego.IterateDeltas(node, deltas, func(entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		inserts = append(inserts, string(delta.Insert))
+		return nil
+	}))
+	assert.Equal(t, []string{"testing"}, inserts)
+}
+
+func TestClient_Amend_and_OpenFile(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	// Disable content cache so content is read lazily
+	client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithContentCache(spaceclientgo.NewMemoryContentCache(0)))
+	content := "The quick brown fox jumps over the lazy dog"
+	ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader(content))
+	testinggo.AssertNoError(t, err)
+
+	metaId := base64.RawURLEncoding.EncodeToString(ref.RecordHash)
+	deltas := spacego.OpenDeltaChannel(metaId)
+	testinggo.AssertNoError(t, deltas.Load(node.Cache(), nil))
+	testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+		Offset: 4,
+		Delete: 5,
+		Insert: []byte("slow"),
+	}))
+	testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+		Offset: 39,
+		Delete: 3,
+		Insert: []byte("cat"),
+	}))
+	expected := "The slow brown fox jumps over the lazy cat"
+
+	file, err := client.OpenFile(node, ref.RecordHash)
+	testinggo.AssertNoError(t, err)
+	defer file.Close()
+
+	bytes, err := ioutil.ReadAll(file)
+	testinggo.AssertNoError(t, err)
+	assert.Equal(t, expected, string(bytes))
+
+	size, err := file.Seek(0, io.SeekEnd)
+	testinggo.AssertNoError(t, err)
+	assert.Equal(t, int64(len(expected)), size)
+
+	_, err = file.Seek(9, io.SeekStart)
+	testinggo.AssertNoError(t, err)
+	buffer := make([]byte, 15)
+	_, err = io.ReadFull(file, buffer)
+	testinggo.AssertNoError(t, err)
+	assert.Equal(t, expected[9:24], string(buffer))
+}
+
+func TestClientReadFileRange(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	content := "The quick brown fox jumps over the lazy dog"
+	ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader(content))
+	testinggo.AssertNoError(t, err)
+
+	for name, tt := range map[string]struct {
+		offset, length int64
+		expected       string
+	}{
+		"Start":     {0, 9, "The quick"},
+		"Middle":    {10, 9, "brown fox"},
+		"End":       {40, 3, "dog"},
+		"Overflow":  {40, 10, "dog"},
+		"Empty":     {10, 0, ""},
+		"EndOfFile": {43, 10, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			reader, err := client.ReadFileRange(node, ref.RecordHash, tt.offset, tt.length)
+			testinggo.AssertNoError(t, err)
+			bytes, err := ioutil.ReadAll(reader)
+			testinggo.AssertNoError(t, err)
+			assert.Equal(t, tt.expected, string(bytes))
+		})
+	}
+	t.Run("OutOfFile", func(t *testing.T) {
+		_, err := client.ReadFileRange(node, ref.RecordHash, 50, 10)
+		testinggo.AssertError(t, "Invalid range: offset 50 is beyond size 43", err)
+	})
+}
+
+func TestClient_Amend_and_ReadFileAt(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+
+	metaId := base64.RawURLEncoding.EncodeToString(ref.RecordHash)
+	deltas := spacego.OpenDeltaChannel(metaId)
+	testinggo.AssertNoError(t, deltas.Load(node.Cache(), nil))
+
+	testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+		Offset: 4,
+		Delete: 3,
+		Insert: []byte("foobar"),
+	}))
+	testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+		Delete: 7,
+	}))
+
+	var entries []*bcgo.BlockEntry
+	testinggo.AssertNoError(t, spacego.IterateDeltas(node, deltas, func(entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		entries = append(entries, entry)
+		return nil
+	}))
+	amended := entries[len(entries)-2]
+
+	t.Run("Timestamp", func(t *testing.T) {
+		reader, err := client.ReadFileAt(node, ref.RecordHash, amended.Record.Timestamp)
+		testinggo.AssertNoError(t, err)
+		bytes, err := ioutil.ReadAll(reader)
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, "testfoobar", string(bytes))
+	})
+	t.Run("Before", func(t *testing.T) {
+		reader, err := client.ReadFileAt(node, ref.RecordHash, 0)
+		testinggo.AssertNoError(t, err)
+		bytes, err := ioutil.ReadAll(reader)
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, "", string(bytes))
+	})
+	t.Run("Version", func(t *testing.T) {
+		reader, err := client.ReadFileVersion(node, ref.RecordHash, amended.RecordHash)
+		testinggo.AssertNoError(t, err)
+		bytes, err := ioutil.ReadAll(reader)
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, "testfoobar", string(bytes))
+	})
+	t.Run("Unknown Version", func(t *testing.T) {
+		_, err := client.ReadFileVersion(node, ref.RecordHash, []byte("unknown"))
+		testinggo.AssertError(t, "Could not find version: dW5rbm93bg", err)
+	})
+}
+
+func TestClientHistory(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+
+	metaId := base64.RawURLEncoding.EncodeToString(ref.RecordHash)
+	deltas := spacego.OpenDeltaChannel(metaId)
+	testinggo.AssertNoError(t, deltas.Load(node.Cache(), nil))
+
+	testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+		Offset: 4,
+		Delete: 3,
+		Insert: []byte("foobar"),
+	}))
+
+	var (
+		inserted, deleted uint64
+		last              *bcgo.BlockEntry
+	)
+	testinggo.AssertNoError(t, client.History(node, ref.RecordHash, func(block []byte, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		assert.Equal(t, alias, entry.Record.Creator)
+		assert.Equal(t, true, len(block) > 0)
+		if last != nil {
+			assert.Equal(t, true, last.Record.Timestamp <= entry.Record.Timestamp)
+		}
+		last = entry
+		inserted += uint64(len(delta.Insert))
+		deleted += delta.Delete
+		return nil
+	}))
+	assert.Equal(t, uint64(13), inserted)
+	assert.Equal(t, uint64(3), deleted)
+}
+
+func TestClientDiff(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+
+	t.Run("Text", func(t *testing.T) {
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"))
+		testinggo.AssertNoError(t, err)
+		var records [][]byte
+		testinggo.AssertNoError(t, client.History(node, ref.RecordHash, func(block []byte, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+			records = append(records, entry.RecordHash)
+			return nil
+		}))
+		from := records[len(records)-1]
+
+		w, err := client.WriteFile(node, nil, ref.RecordHash)
+		testinggo.AssertNoError(t, err)
+		_, err = w.Write([]byte("a\nb\nc\nd\nE\nf\ng\nh\ni\nj\nk"))
+		testinggo.AssertNoError(t, err)
+		testinggo.AssertNoError(t, w.Close())
+
+		reader, err := client.Diff(node, ref.RecordHash, from, nil)
+		testinggo.AssertNoError(t, err)
+		diff, err := ioutil.ReadAll(reader)
+		testinggo.AssertNoError(t, err)
+		mId := base64.RawURLEncoding.EncodeToString(ref.RecordHash)
+		assert.Equal(t, "--- "+mId+"@"+base64.RawURLEncoding.EncodeToString(from)+"\n"+
+			"+++ "+mId+"@current\n"+
+			"@@ -2,9 +2,10 @@\n"+
+			" b\n"+
+			" c\n"+
+			" d\n"+
+			"-e\n"+
+			"+E\n"+
+			" f\n"+
+			" g\n"+
+			" h\n"+
+			" i\n"+
+			" j\n"+
+			"+k\n"+
+			"\\ No newline at end of file\n", string(diff))
+	})
+	t.Run("Binary", func(t *testing.T) {
+		ref, err := client.Add(node, nil, "test", "application/octet-stream", bytes.NewReader([]byte{0, 1, 2, 3}))
+		testinggo.AssertNoError(t, err)
+		metaId := base64.RawURLEncoding.EncodeToString(ref.RecordHash)
+		deltas := spacego.OpenDeltaChannel(metaId)
+		testinggo.AssertNoError(t, deltas.Load(node.Cache(), nil))
+		testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+			Offset: 1,
+			Delete: 2,
+			Insert: []byte{9, 9, 9},
+		}))
+
+		reader, err := client.Diff(node, ref.RecordHash, nil, nil)
+		testinggo.AssertNoError(t, err)
+		diff, err := ioutil.ReadAll(reader)
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, "Binary files "+metaId+"@empty and "+metaId+"@current differ\n"+
+			"Size: 0 -> 5 bytes\n"+
+			"Changed: 0 bytes at offset 0 replaced with 5 bytes\n", string(diff))
+	})
+}
+
+func TestClientRevert(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing=true"))
+	testinggo.AssertNoError(t, err)
+
+	var records [][]byte
+	testinggo.AssertNoError(t, client.History(node, ref.RecordHash, func(block []byte, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		records = append(records, entry.RecordHash)
+		return nil
+	}))
+	version := records[len(records)-1]
+
+	w, err := client.WriteFile(node, nil, ref.RecordHash)
+	testinggo.AssertNoError(t, err)
+	_, err = w.Write([]byte("tasting=false"))
+	testinggo.AssertNoError(t, err)
+	testinggo.AssertNoError(t, w.Close())
+	assertFile(t, client, node, ref.RecordHash, 13, "tasting=false")
+
+	testinggo.AssertNoError(t, client.Revert(node, nil, ref.RecordHash, version))
+	assertFile(t, client, node, ref.RecordHash, 12, "testing=true")
+}
+
+func TestClientContentCache(t *testing.T) {
+	directory := t.TempDir()
+	for name, create := range map[string]func(uint64) spaceclientgo.ContentCache{
+		"Memory": spaceclientgo.NewMemoryContentCache,
+		"FileSystem": func(limit uint64) spaceclientgo.ContentCache {
+			c, err := spaceclientgo.NewFileSystemContentCache(filepath.Join(directory, strconv.FormatUint(limit, 10)), limit)
+			testinggo.AssertNoError(t, err)
+			return c
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("ReadFile", func(t *testing.T) {
+				alias := "Tester"
+				cache := cache.NewMemory(10)
+				node := makeNode(t, alias, cache, nil)
+				contents := create(1024)
+				client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithContentCache(contents))
+				ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+				testinggo.AssertNoError(t, err)
+
+				assertFile(t, client, node, ref.RecordHash, 7, "testing")
+				c, ok := contents.Content(alias + "/" + base64.RawURLEncoding.EncodeToString(ref.RecordHash))
+				assert.Equal(t, true, ok)
+				assert.Equal(t, "testing", string(c.Data))
+				assertFile(t, client, node, ref.RecordHash, 7, "testing")
+
+				// Cached content is invalidated when the delta channel changes
+				metaId := base64.RawURLEncoding.EncodeToString(ref.RecordHash)
+				deltas := spacego.OpenDeltaChannel(metaId)
+				testinggo.AssertNoError(t, deltas.Load(node.Cache(), nil))
+				testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+					Offset: 7,
+					Insert: []byte("123"),
+				}))
+				assertFile(t, client, node, ref.RecordHash, 10, "testing123")
+
+				reader, err := client.OpenFile(node, ref.RecordHash)
+				testinggo.AssertNoError(t, err)
+				bytes, err := ioutil.ReadAll(reader)
+				testinggo.AssertNoError(t, err)
+				assert.Equal(t, "testing123", string(bytes))
+			})
+			t.Run("TooLarge", func(t *testing.T) {
+				contents := create(4)
+				testinggo.AssertNoError(t, contents.PutContent("a", &spaceclientgo.CachedContent{
+					Data: []byte("testing"),
+				}))
+				_, ok := contents.Content("a")
+				assert.Equal(t, false, ok)
+			})
+		})
+	}
+}
+
+func TestMemoryContentCache_Eviction(t *testing.T) {
+	contents := spaceclientgo.NewMemoryContentCache(8)
+	for _, key := range []string{"a", "b"} {
+		testinggo.AssertNoError(t, contents.PutContent(key, &spaceclientgo.CachedContent{
+			Data: []byte("test"),
+		}))
+	}
+	// Use a so b is least recently used
+	_, ok := contents.Content("a")
+	assert.Equal(t, true, ok)
+	testinggo.AssertNoError(t, contents.PutContent("c", &spaceclientgo.CachedContent{
+		Data: []byte("test"),
+	}))
+	for key, expected := range map[string]bool{
+		"a": true,
+		"b": false,
+		"c": true,
+	} {
+		_, ok := contents.Content(key)
+		assert.Equal(t, expected, ok, key)
+	}
+}
+
+func TestFileSystemContentCache_Limit(t *testing.T) {
+	directory := t.TempDir()
+	contents, err := spaceclientgo.NewFileSystemContentCache(directory, 4096)
+	testinggo.AssertNoError(t, err)
+	for i := 0; i < 10; i++ {
+		testinggo.AssertNoError(t, contents.PutContent(strconv.Itoa(i), &spaceclientgo.CachedContent{
+			Data: bytes.Repeat([]byte("x"), 1000),
+		}))
+	}
+	files, err := ioutil.ReadDir(directory)
+	testinggo.AssertNoError(t, err)
+	var size int64
+	for _, f := range files {
+		size += f.Size()
+	}
+	assert.True(t, size <= 4096)
+	_, ok := contents.Content("9")
+	assert.True(t, ok)
+}
+
+func TestClientWatchFile(t *testing.T) {
+	setup := func(t *testing.T) (spaceclientgo.SpaceClient, *test.NotifyingPeer, bcgo.Node, []byte, bcgo.Channel, chan struct{}) {
+		t.Helper()
+		peer := test.NewNotifyingPeer(t)
+		node := makeNode(t, "Tester", cache.NewMemory(10), nil)
+		client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithNotifier(spaceclientgo.NewTCPNotifier(peer.Address())))
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		// Simulate a remote writer with a separate instance of the delta channel
+		deltas := spacego.OpenDeltaChannel(base64.RawURLEncoding.EncodeToString(ref.RecordHash))
+		testinggo.AssertNoError(t, deltas.Load(node.Cache(), nil))
+		updates := make(chan struct{}, 10)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		client.WatchFile(ctx, node, ref.RecordHash, func() {
+			updates <- struct{}{}
+		})
+		peer.AwaitSubscription(t, deltas.Name())
+		return client, peer, node, ref.RecordHash, deltas, updates
+	}
+	await := func(t *testing.T, updates chan struct{}, timeout time.Duration) {
+		t.Helper()
+		select {
+		case <-updates:
+		case <-time.After(timeout):
+			t.Fatal("Expected update")
+		}
+	}
+	t.Run("Pushed", func(t *testing.T) {
+		client, peer, node, metaId, deltas, updates := setup(t)
+		testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+			Offset: 7,
+			Insert: []byte("123"),
+		}))
+		peer.Notify(deltas.Name(), deltas.Head())
+		// Notification arrives well before the first poll
+		await(t, updates, 500*time.Millisecond)
+		assertFile(t, client, node, metaId, 10, "testing123")
+	})
+	t.Run("Fallback", func(t *testing.T) {
+		client, peer, node, metaId, deltas, updates := setup(t)
+		peer.Close()
+		testinggo.AssertNoError(t, client.Amend(node, nil, deltas, &spacego.Delta{
+			Offset: 7,
+			Insert: []byte("123"),
+		}))
+		// Polling resumes once subscription ends
+		await(t, updates, 5*time.Second)
+		assertFile(t, client, node, metaId, 10, "testing123")
+	})
+}
+
+func TestClientWatchFileChanges(t *testing.T) {
+	setup := func(t *testing.T, callback spaceclientgo.FileChangeCallback) (spaceclientgo.FileWatch, *test.NotifyingPeer, bcgo.Node, bcgo.Channel) {
+		t.Helper()
+		peer := test.NewNotifyingPeer(t)
+		cache := cache.NewMemory(10)
+		n := makeNode(t, "Tester", cache, nil)
+		client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithNotifier(spaceclientgo.NewTCPNotifier(peer.Address())))
+		ref, err := client.Add(n, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		watch, err := client.WatchFileChanges(context.Background(), n, ref.RecordHash, callback)
+		testinggo.AssertNoError(t, err)
+		t.Cleanup(watch.Stop)
+		// Simulate another device using the same account
+		device := node.New(n.Account(), cache, nil)
+		deltas := spacego.OpenDeltaChannel(base64.RawURLEncoding.EncodeToString(ref.RecordHash))
+		testinggo.AssertNoError(t, deltas.Load(cache, nil))
+		peer.AwaitSubscription(t, deltas.Name())
+		return watch, peer, device, deltas
+	}
+	t.Run("Ordered", func(t *testing.T) {
+		changes := make(chan *spaceclientgo.FileChange, 10)
+		_, peer, device, deltas := setup(t, func(change *spaceclientgo.FileChange) error {
+			changes <- change
+			return nil
+		})
+		client := spaceclientgo.NewSpaceClient()
+		testinggo.AssertNoError(t, client.Amend(device, nil, deltas, &spacego.Delta{
+			Offset: 7,
+			Insert: []byte("1"),
+		}, &spacego.Delta{
+			Offset: 8,
+			Insert: []byte("2"),
+		}))
+		first := deltas.Head()
+		testinggo.AssertNoError(t, client.Amend(device, nil, deltas, &spacego.Delta{
+			Offset: 9,
+			Insert: []byte("3"),
+		}))
+		second := deltas.Head()
+		// Both updates are delivered by a single notification
+		peer.Notify(deltas.Name(), second)
+
+		var inserts []string
+		for len(inserts) < 3 {
+			select {
+			case change := <-changes:
+				inserts = append(inserts, string(change.Delta.Insert))
+				assert.Equal(t, "Tester", change.Author)
+				assert.Equal(t, second, change.Head)
+				if len(inserts) < 3 {
+					assert.Equal(t, first, change.Block)
+				} else {
+					assert.Equal(t, second, change.Block)
+				}
+			case <-time.After(500 * time.Millisecond):
+				t.Fatalf("Expected changes; got %v", inserts)
+			}
+		}
+		assert.Equal(t, []string{"1", "2", "3"}, inserts)
+	})
+	t.Run("CallbackError", func(t *testing.T) {
+		watch, peer, device, deltas := setup(t, func(change *spaceclientgo.FileChange) error {
+			return errors.New("Failed")
+		})
+		testinggo.AssertNoError(t, spaceclientgo.NewSpaceClient().Amend(device, nil, deltas, &spacego.Delta{
+			Offset: 7,
+			Insert: []byte("1"),
+		}))
+		peer.Notify(deltas.Name(), deltas.Head())
+		select {
+		case <-watch.Done():
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Expected watch to end")
+		}
+		testinggo.AssertError(t, "Failed", watch.Err())
+	})
+	t.Run("Stop", func(t *testing.T) {
+		watch, _, _, _ := setup(t, func(change *spaceclientgo.FileChange) error {
+			t.Fatal("Unexpected change")
+			return nil
+		})
+		assert.Nil(t, watch.Err())
+		watch.Stop()
+		select {
+		case <-watch.Done():
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Expected watch to end")
+		}
+		testinggo.AssertError(t, context.Canceled.Error(), watch.Err())
+	})
+	t.Run("Forwarded", func(t *testing.T) {
+		peer := test.NewNotifyingPeer(t)
+		cache := cache.NewMemory(10)
+		alice := makeNode(t, "Alice", cache, nil)
+		bob := makeNode(t, "Bob", cache, nil)
+		testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+		testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+		client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithNotifier(spaceclientgo.NewTCPNotifier(peer.Address())))
+		ref, err := client.Add(alice, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+		changes := make(chan *spaceclientgo.FileChange, 10)
+		watch, err := client.WatchFileChanges(context.Background(), alice, ref.RecordHash, func(change *spaceclientgo.FileChange) error {
+			changes <- change
+			return nil
+		})
+		testinggo.AssertNoError(t, err)
+		t.Cleanup(watch.Stop)
+		deltas := spacego.OpenDeltaChannel(base64.RawURLEncoding.EncodeToString(ref.RecordHash))
+		peer.AwaitSubscription(t, deltas.Name())
+		await := func(t *testing.T) *spaceclientgo.FileChange {
+			t.Helper()
+			select {
+			case change := <-changes:
+				return change
+			case <-time.After(500 * time.Millisecond):
+				t.Fatal("Expected change")
+			}
+			return nil
+		}
+
+		// Another device revokes access, moving the file to a new channel
+		device := node.New(alice.Account(), cache, nil)
+		testinggo.AssertNoError(t, spaceclientgo.NewSpaceClient().Revoke(device, nil, ref.RecordHash, "Bob"))
+		testinggo.AssertNoError(t, deltas.Load(cache, nil))
+		peer.Notify(deltas.Name(), deltas.Head())
+
+		change := await(t)
+		assert.Equal(t, uint64(7), change.Delta.Delete)
+		assert.Equal(t, 1, len(change.Entry.Record.Reference))
+		forwarded := spacego.OpenDeltaChannel(strings.TrimPrefix(change.Entry.Record.Reference[0].ChannelName, spacego.DeltaChannelName("")))
+		assert.Equal(t, "testing", string(await(t).Delta.Insert))
+
+		// Changes in the new channel are delivered
+		peer.AwaitSubscription(t, forwarded.Name())
+		testinggo.AssertNoError(t, forwarded.Load(cache, nil))
+		testinggo.AssertNoError(t, spaceclientgo.NewSpaceClient().Amend(device, nil, forwarded, &spacego.Delta{
+			Offset: 7,
+			Insert: []byte("1"),
+		}))
+		peer.Notify(forwarded.Name(), forwarded.Head())
+		assert.Equal(t, "1", string(await(t).Delta.Insert))
+	})
+}
+
+func TestClientWatchMetas(t *testing.T) {
+	peer := test.NewNotifyingPeer(t)
+	cache := cache.NewMemory(10)
+	n := makeNode(t, "Tester", cache, nil)
+	client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithNotifier(spaceclientgo.NewTCPNotifier(peer.Address())))
+	existing, err := client.Add(n, nil, "existing", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+
+	metas := make(chan *spacego.Meta, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.WatchMetas(ctx, n, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		metas <- meta
+		return nil
+	})
+	channel := spacego.MetaChannelName("Tester")
+	peer.AwaitSubscription(t, channel)
+
+	await := func(t *testing.T, name string) {
+		t.Helper()
+		select {
+		case meta := <-metas:
+			assert.Equal(t, name, meta.Name)
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("Expected meta: %s", name)
+		}
+	}
+
+	t.Run("Local", func(t *testing.T) {
+		_, err := client.Add(n, nil, "local", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		await(t, "local")
+	})
+	t.Run("Remote", func(t *testing.T) {
+		// Simulate another device using the same account
+		device := node.New(n.Account(), cache, nil)
+		_, err := spaceclientgo.NewSpaceClient().Add(device, nil, "remote", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		metas := spacego.OpenMetaChannel("Tester")
+		testinggo.AssertNoError(t, metas.Load(cache, nil))
+		peer.Notify(channel, metas.Head())
+		await(t, "remote")
+	})
+	t.Run("Update", func(t *testing.T) {
+		testinggo.AssertNoError(t, client.UpdateMeta(n, nil, existing.RecordHash, "renamed", "text/plain"))
+		await(t, "renamed")
+	})
+	t.Run("Delete", func(t *testing.T) {
+		testinggo.AssertNoError(t, client.Delete(n, nil, existing.RecordHash))
+		select {
+		case meta := <-metas:
+			t.Fatalf("Unexpected meta: %s", meta.Name)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+// cancellingListener cancels a context as soon as mining starts, and counts the blocks mined.
+type cancellingListener struct {
+	cancel context.CancelFunc
+	blocks int
+}
+
+func (l *cancellingListener) OnMiningStarted(bcgo.Channel, uint64) {
+	l.cancel()
+}
+
+func (l *cancellingListener) OnNewMaxOnes(bcgo.Channel, uint64, uint64) {}
+
+func (l *cancellingListener) OnMiningThresholdReached(bcgo.Channel, []byte, *bcgo.Block) {
+	l.blocks++
+}
+
+func TestClientContext(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), nil)
+		client := spaceclientgo.NewSpaceClient()
+		ctx := context.Background()
+		ref, err := client.AddContext(ctx, node, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		reader, err := client.ReadFileContext(ctx, node, ref.RecordHash)
+		testinggo.AssertNoError(t, err)
+		data, err := ioutil.ReadAll(reader)
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, "testing", string(data))
+	})
+	t.Run("Cancelled", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), nil)
+		client := spaceclientgo.NewSpaceClient()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := client.AddContext(ctx, node, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertError(t, context.Canceled.Error(), err)
+		testinggo.AssertError(t, context.Canceled.Error(), client.AllMetasContext(ctx, node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			t.Fatalf("Unexpected meta: %s", meta.Name)
+			return nil
+		}))
+		// Nothing was written
+		testinggo.AssertNoError(t, client.AllMetas(node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			t.Fatalf("Unexpected meta: %s", meta.Name)
+			return nil
+		}))
+	})
+	t.Run("CancelledWhileMining", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), nil)
+		client := spaceclientgo.NewSpaceClient()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		listener := &cancellingListener{
+			cancel: cancel,
+		}
+		_, err := client.AddContext(ctx, node, listener, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertError(t, context.Canceled.Error(), err)
+		// Mining is not interrupted, so the meta block was mined, but the content was not written
+		assert.Equal(t, 1, listener.blocks)
+		var metaIds [][]byte
+		testinggo.AssertNoError(t, client.AllMetas(node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			metaIds = append(metaIds, entry.RecordHash)
+			return nil
+		}))
+		assert.Equal(t, 1, len(metaIds))
+		assertFile(t, client, node, metaIds[0], 0, "")
+	})
+	t.Run("Deadline", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), test.NewBlockingNetwork())
+		client := spaceclientgo.NewSpaceClient()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		result := make(chan error, 1)
+		go func() {
+			result <- client.AllMetasContext(ctx, node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+				return nil
+			})
+		}()
+		select {
+		case err := <-result:
+			testinggo.AssertError(t, context.DeadlineExceeded.Error(), err)
+		case <-time.After(time.Second):
+			t.Fatal("Expected deadline to end call")
+		}
+		// Calls made after the deadline fail without writing
+		_, err := client.AddContext(ctx, node, nil, "test", "text/plain", nil)
+		testinggo.AssertError(t, context.DeadlineExceeded.Error(), err)
+	})
+}
+
+func TestClientErrors(t *testing.T) {
+	unknown := []byte("unknown")
+	t.Run("FileNotFound", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), nil)
+		client := spaceclientgo.NewSpaceClient()
+		err := client.MetaForHash(node, unknown, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			t.Fatalf("Unexpected meta: %s", meta.Name)
+			return nil
+		})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+		testinggo.AssertError(t, "Could not find file: dW5rbm93bg", err)
+		_, err = client.ReadFile(node, unknown)
+		assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+		_, err = client.AddTag(node, nil, unknown, []string{"foo"})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	})
+	t.Run("VersionNotFound", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), nil)
+		client := spaceclientgo.NewSpaceClient()
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		_, err = client.ReadFileVersion(node, ref.RecordHash, unknown)
+		assert.True(t, errors.Is(err, spaceclientgo.ErrVersionNotFound))
+	})
+	t.Run("AccessDenied", func(t *testing.T) {
+		cache := cache.NewMemory(10)
+		alice := makeNode(t, "Alice", cache, nil)
+		bob := makeNode(t, "Bob", cache, nil)
+		testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+		testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+		client := spaceclientgo.NewSpaceClient()
+		ref, err := client.Add(alice, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+
+		// Recipient can read but not change the file
+		var names []string
+		testinggo.AssertNoError(t, client.MetaForHash(bob, ref.RecordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			names = append(names, meta.Name)
+			return nil
+		}))
+		assert.Equal(t, []string{"test"}, names)
+		err = client.UpdateMeta(bob, nil, ref.RecordHash, "renamed", "")
+		assert.True(t, errors.Is(err, spaceclientgo.ErrAccessDenied))
+		err = client.Delete(bob, nil, ref.RecordHash)
+		assert.True(t, errors.Is(err, spaceclientgo.ErrAccessDenied))
+	})
+	t.Run("NotDecryptable", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), nil)
+		other := makeNode(t, "Other", node.Cache(), nil)
+		client := spaceclientgo.NewSpaceClient()
+		// Meta data in the account's channel which only another alias can decrypt
+		metas := spacego.OpenMetaChannel("Tester")
+		data, err := proto.Marshal(&spacego.Meta{
+			Name: "test",
+			Type: "text/plain",
+		})
+		testinggo.AssertNoError(t, err)
+		ref, err := node.Write(bcgo.Timestamp(), metas, []bcgo.Identity{other.Account()}, nil, data)
+		testinggo.AssertNoError(t, err)
+		_, _, err = bcgo.Mine(node, metas, spacego.THRESHOLD_CUSTOMER, nil)
+		testinggo.AssertNoError(t, err)
+
+		err = client.MetaForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			t.Fatalf("Unexpected meta: %s", meta.Name)
+			return nil
+		})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrNotDecryptable))
+		err = client.MetaForHash(node, unknown, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			return nil
+		})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	})
+	t.Run("Rejected", func(t *testing.T) {
+		network := test.NewAcceptingNetwork()
+		network.BroadcastError = errors.New("Quota exceeded")
+		node := makeNode(t, "Tester", cache.NewMemory(10), network)
+		client := spaceclientgo.NewSpaceClient()
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+		// File is still mined locally
+		assertFile(t, client, node, ref.RecordHash, 7, "testing")
+	})
+	t.Run("NetworkUnavailable", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), test.NewFailingNetwork())
+		client := spaceclientgo.NewSpaceClient()
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+		// File is still mined locally
+		assertFile(t, client, node, ref.RecordHash, 7, "testing")
+
+		err = client.MetaForHash(node, unknown, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			return nil
+		})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+
+		// References to changes mined locally are returned with the error
+		ctx := context.Background()
+		ref, err = client.AddContext(ctx, node, nil, "test", "text/plain", strings.NewReader("testing"))
+		assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+		assert.True(t, ref != nil)
+		refs, err := client.AddTagContext(ctx, node, nil, ref.RecordHash, []string{"foo"})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+		assert.Equal(t, 1, len(refs))
+		refs, err = client.RemoveTagContext(ctx, node, nil, ref.RecordHash, []string{"foo"})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+		assert.Equal(t, 1, len(refs))
+	})
+}
+
+// recordingLogger records the messages logged at each level.
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) {
+	l.messages = append(l.messages, "DEBUG "+msg)
+}
+
+func (l *recordingLogger) Info(msg string, args ...interface{}) {
+	l.messages = append(l.messages, "INFO "+msg)
+}
+
+func (l *recordingLogger) Warn(msg string, args ...interface{}) {
+	l.messages = append(l.messages, "WARN "+msg)
+}
+
+func (l *recordingLogger) Error(msg string, args ...interface{}) {
+	l.messages = append(l.messages, "ERROR "+msg)
+}
+
+func TestClientEvents(t *testing.T) {
+	record := func(events *[]*spaceclientgo.Event) spaceclientgo.EventHook {
+		return func(e *spaceclientgo.Event) {
+			*events = append(*events, e)
+		}
+	}
+	types := func(events []*spaceclientgo.Event, channel string) (types []spaceclientgo.EventType) {
+		for _, e := range events {
+			if e.Channel == channel {
+				types = append(types, e.Type)
+			}
+		}
+		return
+	}
+	t.Run("Pushed", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), test.NewAcceptingNetwork())
+		logger := &recordingLogger{}
+		var events []*spaceclientgo.Event
+		client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithLogger(logger), spaceclientgo.WithEventHook(record(&events)))
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		deltas := spacego.DeltaChannelName(base64.RawURLEncoding.EncodeToString(ref.RecordHash))
+		assert.Equal(t, []spaceclientgo.EventType{
+			spaceclientgo.EVENT_REFRESH_FAILED,
+			spaceclientgo.EVENT_DELTA_WRITTEN,
+			spaceclientgo.EVENT_MINED,
+			spaceclientgo.EVENT_PUSHED,
+		}, types(events, deltas))
+		for _, e := range events {
+			if e.Type != spaceclientgo.EVENT_REFRESH_FAILED {
+				assert.True(t, len(e.Hash) > 0)
+			}
+		}
+		assert.Contains(t, logger.messages, "WARN Refresh failed")
+		assert.Contains(t, logger.messages, "DEBUG Pushed channel")
+	})
+	t.Run("PushFailed", func(t *testing.T) {
+		node := makeNode(t, "Tester", cache.NewMemory(10), test.NewFailingNetwork())
+		logger := &recordingLogger{}
+		var events []*spaceclientgo.Event
+		client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithLogger(logger), spaceclientgo.WithEventHook(record(&events)))
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+		deltas := spacego.DeltaChannelName(base64.RawURLEncoding.EncodeToString(ref.RecordHash))
+		assert.Equal(t, []spaceclientgo.EventType{
+			spaceclientgo.EVENT_REFRESH_FAILED,
+			spaceclientgo.EVENT_DELTA_WRITTEN,
+			spaceclientgo.EVENT_MINED,
+			spaceclientgo.EVENT_PUSH_FAILED,
+		}, types(events, deltas))
+		assert.Contains(t, logger.messages, "WARN Push failed")
+	})
+}
+
 func TestClientAllMetas(t *testing.T) {
 	alias := "Tester"
 	cache := cache.NewMemory(10)
@@ -222,20 +1385,765 @@ func TestClientMetaForHash(t *testing.T) {
 	}
 }
 
+func TestClientUpdateMeta(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	ref, err := client.Add(node, nil, "tset", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+
+	testinggo.AssertNoError(t, client.UpdateMeta(node, nil, ref.RecordHash, "test", ""))
+	assertMeta(t, client, node, "test", "text/plain")
+
+	testinggo.AssertNoError(t, client.UpdateMeta(node, nil, ref.RecordHash, "", "text/markdown"))
+	assertMeta(t, client, node, "test", "text/markdown")
+
+	testinggo.AssertNoError(t, client.MetaForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		assert.Equal(t, ref.RecordHash, entry.RecordHash)
+		assert.Equal(t, "test", meta.Name)
+		return nil
+	}))
+
+	var count int
+	testinggo.AssertNoError(t, client.SearchMeta(node, spacego.NewNameFilter("tset"), func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 0, count)
+
+	assertFile(t, client, node, ref.RecordHash, 7, "testing")
+}
+
+func TestClientDelete(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+	assertFile(t, client, node, ref.RecordHash, 7, "testing")
+	_, err = client.Add(node, nil, "test", spaceclientgo.MIME_TYPE_DELETED, strings.NewReader("testing"))
+	testinggo.AssertError(t, "Reserved MIME type: "+spaceclientgo.MIME_TYPE_DELETED, err)
+	testinggo.AssertError(t, "Reserved MIME type: "+spaceclientgo.MIME_TYPE_PURGED, client.UpdateMeta(node, nil, ref.RecordHash, "", spaceclientgo.MIME_TYPE_PURGED))
+	refs, err := client.AddAll(node, nil, []spaceclientgo.FileSpec{
+		{Name: "test", Mime: "text/plain"},
+		{Name: "test", Mime: spaceclientgo.MIME_TYPE_DELETED},
+	})
+	testinggo.AssertError(t, "Reserved MIME type: "+spaceclientgo.MIME_TYPE_DELETED, err)
+	assert.Equal(t, 0, len(refs))
+	_, err = client.AddTag(node, nil, ref.RecordHash, []string{"foo"})
+	testinggo.AssertNoError(t, err)
+
+	testinggo.AssertNoError(t, client.Delete(node, nil, ref.RecordHash))
+
+	count := func(list func(spacego.MetaCallback) error) int {
+		t.Helper()
+		var count int
+		testinggo.AssertNoError(t, list(func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			count++
+			return nil
+		}))
+		return count
+	}
+	assert.Equal(t, 0, count(func(callback spacego.MetaCallback) error {
+		return client.AllMetas(node, callback)
+	}))
+	assert.Equal(t, 0, count(func(callback spacego.MetaCallback) error {
+		return client.SearchMeta(node, spacego.NewNameFilter("test"), callback)
+	}))
+	assert.Equal(t, 0, count(func(callback spacego.MetaCallback) error {
+		return client.SearchTag(node, spacego.NewTagFilter("foo"), callback)
+	}))
+	testinggo.AssertNoError(t, client.Trash(node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		assert.Equal(t, ref.RecordHash, entry.RecordHash)
+		assert.Equal(t, "test", meta.Name)
+		assert.Equal(t, "text/plain", meta.Type)
+		return nil
+	}))
+	testinggo.AssertError(t, "Could not find file: "+base64.RawURLEncoding.EncodeToString(ref.RecordHash), client.Delete(node, nil, ref.RecordHash))
+
+	// Files in the trash cannot be read or changed
+	_, err = client.ReadFile(node, ref.RecordHash)
+	assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	_, err = client.OpenFile(node, ref.RecordHash)
+	assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	_, err = client.AddTag(node, nil, ref.RecordHash, []string{"bar"})
+	assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	_, err = client.RemoveTag(node, nil, ref.RecordHash, []string{"foo"})
+	assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	assert.True(t, errors.Is(client.UpdateMeta(node, nil, ref.RecordHash, "renamed", ""), spaceclientgo.ErrFileNotFound))
+
+	t.Run("Restore", func(t *testing.T) {
+		testinggo.AssertNoError(t, client.Restore(node, nil, ref.RecordHash))
+		assertMeta(t, client, node, "test", "text/plain")
+		assertFile(t, client, node, ref.RecordHash, 7, "testing")
+		assert.Equal(t, 0, count(func(callback spacego.MetaCallback) error {
+			return client.Trash(node, callback)
+		}))
+	})
+	t.Run("EmptyTrash", func(t *testing.T) {
+		testinggo.AssertNoError(t, client.Delete(node, nil, ref.RecordHash))
+		testinggo.AssertNoError(t, client.EmptyTrash(node, nil))
+		assert.Equal(t, 0, count(func(callback spacego.MetaCallback) error {
+			return client.Trash(node, callback)
+		}))
+		assert.Equal(t, 0, count(func(callback spacego.MetaCallback) error {
+			return client.AllMetas(node, callback)
+		}))
+		testinggo.AssertError(t, "Could not find file in trash: "+base64.RawURLEncoding.EncodeToString(ref.RecordHash), client.Restore(node, nil, ref.RecordHash))
+	})
+}
+
+func TestClientShare(t *testing.T) {
+	cache := cache.NewMemory(10)
+	alice := makeNode(t, "Alice", cache, nil)
+	bob := makeNode(t, "Bob", cache, nil)
+	testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+	testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+	client := spaceclientgo.NewSpaceClient()
+	name := "test"
+	mime := "text/plain"
+	ref, err := client.Add(alice, nil, name, mime, strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+
+	testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+
+	assertMeta(t, client, alice, name, mime)
+	assertFile(t, client, alice, ref.RecordHash, 7, "testing")
+	assertFile(t, client, bob, ref.RecordHash, 7, "testing")
+
+	// Subsequent changes are readable by both
+	w, err := client.WriteFile(alice, nil, ref.RecordHash)
+	testinggo.AssertNoError(t, err)
+	_, err = w.Write([]byte("testing=true"))
+	testinggo.AssertNoError(t, err)
+	testinggo.AssertNoError(t, w.Close())
+
+	assertFile(t, client, alice, ref.RecordHash, 12, "testing=true")
+	assertFile(t, client, bob, ref.RecordHash, 12, "testing=true")
+
+	t.Run("ForeignDelta", func(t *testing.T) {
+		// Another alias writes a delta into the file's channel granting access to itself, and to an unregistered alias
+		mallory := makeNode(t, "Mallory", cache, nil)
+		testinggo.AssertNoError(t, aliasgo.Register(mallory, nil))
+		eve := makeNode(t, "Eve", cache, nil)
+		mId := base64.RawURLEncoding.EncodeToString(ref.RecordHash)
+		channel := mallory.OpenChannel(spacego.DeltaChannelName(mId), func() bcgo.Channel {
+			return spacego.OpenDeltaChannel(mId)
+		})
+		testinggo.AssertNoError(t, channel.Load(mallory.Cache(), nil))
+		data, err := proto.Marshal(&spacego.Delta{})
+		testinggo.AssertNoError(t, err)
+		_, err = mallory.Write(bcgo.Timestamp(), channel, []bcgo.Identity{alice.Account(), mallory.Account(), eve.Account()}, nil, data)
+		testinggo.AssertNoError(t, err)
+		_, _, err = bcgo.Mine(mallory, channel, spacego.THRESHOLD_CUSTOMER, nil)
+		testinggo.AssertNoError(t, err)
+
+		// Subsequent changes are only readable by aliases granted access by the owner
+		w, err := client.WriteFile(alice, nil, ref.RecordHash)
+		testinggo.AssertNoError(t, err)
+		_, err = w.Write([]byte("testing=false"))
+		testinggo.AssertNoError(t, err)
+		testinggo.AssertNoError(t, w.Close())
+
+		assertFile(t, client, alice, ref.RecordHash, 13, "testing=false")
+		assertFile(t, client, bob, ref.RecordHash, 13, "testing=false")
+		testinggo.AssertNoError(t, channel.Load(cache, nil))
+		testinggo.AssertNoError(t, bcgo.Iterate(channel.Name(), channel.Head(), nil, cache, nil, func(hash []byte, block *bcgo.Block) error {
+			for _, e := range block.Entry {
+				if e.Record.Creator != "Alice" {
+					continue
+				}
+				for _, a := range e.Record.Access {
+					assert.NotEqual(t, "Mallory", a.Alias)
+					assert.NotEqual(t, "Eve", a.Alias)
+				}
+			}
+			return nil
+		}))
+	})
+}
+
+func TestClientShareLarge(t *testing.T) {
+	cache := cache.NewMemory(100)
+	alice := makeNode(t, "Alice", cache, nil)
+	bob := makeNode(t, "Bob", cache, nil)
+	testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+	testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+	client := spaceclientgo.NewSpaceClient()
+	// Content spans several deltas
+	content := strings.Repeat("0123456789", int(2*spacego.MAX_SIZE_BYTES/10)+1)
+	ref, err := client.Add(alice, nil, "large", "text/plain", strings.NewReader(content))
+	testinggo.AssertNoError(t, err)
+
+	testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+
+	assertFile(t, client, alice, ref.RecordHash, len(content), content)
+	assertFile(t, client, bob, ref.RecordHash, len(content), content)
+}
+
+func TestClientSharedMetas(t *testing.T) {
+	cache := cache.NewMemory(10)
+	alice := makeNode(t, "Alice", cache, nil)
+	bob := makeNode(t, "Bob", cache, nil)
+	testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+	testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+	client := spaceclientgo.NewSpaceClient()
+	name := "test"
+	mime := "text/plain"
+	ref, err := client.Add(alice, nil, name, mime, strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+
+	testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+
+	var owners []string
+	var metas []*spacego.Meta
+	testinggo.AssertNoError(t, client.SharedMetas(bob, func(owner string, entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		assert.Equal(t, ref.RecordHash, entry.RecordHash)
+		owners = append(owners, owner)
+		metas = append(metas, meta)
+		return nil
+	}))
+	assert.Equal(t, []string{"Alice"}, owners)
+	assert.Equal(t, 1, len(metas))
+	assert.Equal(t, name, metas[0].Name)
+	assert.Equal(t, mime, metas[0].Type)
+
+	// Shared files are not listed as owned
+	testinggo.AssertNoError(t, client.AllMetas(bob, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		t.Fatalf("Unexpected meta: %s", meta.Name)
+		return nil
+	}))
+
+	// Owner has nothing shared with them
+	testinggo.AssertNoError(t, client.SharedMetas(alice, func(owner string, entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		t.Fatalf("Unexpected meta: %s", meta.Name)
+		return nil
+	}))
+
+	t.Run("UnregisteredOwner", func(t *testing.T) {
+		carol := makeNode(t, "Carol", cache, nil)
+		ref, err := client.Add(carol, nil, "unregistered", mime, strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		testinggo.AssertNoError(t, client.Share(carol, nil, ref.RecordHash, "Bob"))
+		var owners []string
+		testinggo.AssertNoError(t, client.SharedMetas(bob, func(owner string, entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			owners = append(owners, owner)
+			return nil
+		}))
+		assert.Equal(t, []string{"Alice"}, owners)
+	})
+	t.Run("ForeignRecord", func(t *testing.T) {
+		// Another alias writes meta data into the owner's channel
+		mallory := makeNode(t, "Mallory", cache, nil)
+		testinggo.AssertNoError(t, aliasgo.Register(mallory, nil))
+		channel := mallory.OpenChannel(spacego.MetaChannelName("Alice"), func() bcgo.Channel {
+			return spacego.OpenMetaChannel("Alice")
+		})
+		testinggo.AssertNoError(t, channel.Load(mallory.Cache(), nil))
+		data, err := proto.Marshal(&spacego.Meta{
+			Name: "forged",
+			Type: mime,
+		})
+		testinggo.AssertNoError(t, err)
+		_, err = mallory.Write(bcgo.Timestamp(), channel, []bcgo.Identity{alice.Account(), bob.Account()}, []*bcgo.Reference{{
+			Timestamp:   ref.Timestamp,
+			ChannelName: spacego.MetaChannelName("Alice"),
+			RecordHash:  ref.RecordHash,
+		}}, data)
+		testinggo.AssertNoError(t, err)
+		_, _, err = bcgo.Mine(mallory, channel, spacego.THRESHOLD_CUSTOMER, nil)
+		testinggo.AssertNoError(t, err)
+		assertMeta(t, client, alice, name, mime)
+		testinggo.AssertNoError(t, client.SharedMetas(bob, func(owner string, entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			assert.Equal(t, name, meta.Name)
+			return nil
+		}))
+	})
+	t.Run("Updated", func(t *testing.T) {
+		// Shared meta data is read again once the owner changes it
+		testinggo.AssertNoError(t, client.UpdateMeta(alice, nil, ref.RecordHash, "renamed", ""))
+		var names []string
+		testinggo.AssertNoError(t, client.SharedMetas(bob, func(owner string, entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			names = append(names, meta.Name)
+			return nil
+		}))
+		assert.Equal(t, []string{"renamed"}, names)
+		testinggo.AssertNoError(t, client.MetaForHash(bob, ref.RecordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			assert.Equal(t, "renamed", meta.Name)
+			return nil
+		}))
+	})
+}
+
+func TestClientRevoke(t *testing.T) {
+	cache := cache.NewMemory(10)
+	alice := makeNode(t, "Alice", cache, nil)
+	bob := makeNode(t, "Bob", cache, nil)
+	testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+	testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+	client := spaceclientgo.NewSpaceClient()
+	name := "test"
+	mime := "text/plain"
+	ref, err := client.Add(alice, nil, name, mime, strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+
+	testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+	assertFile(t, client, bob, ref.RecordHash, 7, "testing")
+
+	testinggo.AssertNoError(t, client.Revoke(alice, nil, ref.RecordHash, "Bob"))
+
+	assertMeta(t, client, alice, name, mime)
+	assertFile(t, client, alice, ref.RecordHash, 7, "testing")
+
+	// Subsequent changes are only readable by the owner
+	w, err := client.WriteFile(alice, nil, ref.RecordHash)
+	testinggo.AssertNoError(t, err)
+	_, err = w.Write([]byte("testing=true"))
+	testinggo.AssertNoError(t, err)
+	testinggo.AssertNoError(t, w.Close())
+
+	assertFile(t, client, alice, ref.RecordHash, 12, "testing=true")
+
+	// File is no longer shared with the revoked alias
+	_, err = client.ReadFile(bob, ref.RecordHash)
+	assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	err = client.MetaForHash(bob, ref.RecordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		return nil
+	})
+	assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	testinggo.AssertNoError(t, client.SharedMetas(bob, func(owner string, entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		t.Fatalf("Unexpected meta: %s", meta.Name)
+		return nil
+	}))
+}
+
+func TestClientRevokeLarge(t *testing.T) {
+	cache := cache.NewMemory(100)
+	alice := makeNode(t, "Alice", cache, nil)
+	bob := makeNode(t, "Bob", cache, nil)
+	testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+	testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+	client := spaceclientgo.NewSpaceClient()
+	// Content spans several deltas
+	content := strings.Repeat("0123456789", int(2*spacego.MAX_SIZE_BYTES/10)+1)
+	ref, err := client.Add(alice, nil, "large", "text/plain", strings.NewReader(content))
+	testinggo.AssertNoError(t, err)
+	testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+
+	testinggo.AssertNoError(t, client.Revoke(alice, nil, ref.RecordHash, "Bob"))
+
+	assertFile(t, client, alice, ref.RecordHash, len(content), content)
+	_, err = client.ReadFile(bob, ref.RecordHash)
+	assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+}
+
 func TestClientSearchMeta(t *testing.T) {
 	// TODO
 }
 
 func TestClientSearchTag(t *testing.T) {
-	// TODO
+	node := makeNode(t, "Tester", cache.NewMemory(100), nil)
+	client := spaceclientgo.NewSpaceClient()
+	for i, tags := range [][]string{
+		{"x", "y"},
+		{"z"},
+		{"y"},
+		nil,
+		{"y", "x", "y"},
+	} {
+		ref, err := client.Add(node, nil, "test"+strconv.Itoa(i), "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		if len(tags) > 0 {
+			_, err = client.AddTag(node, nil, ref.RecordHash, tags)
+			testinggo.AssertNoError(t, err)
+		}
+	}
+	var expected []string
+	testinggo.AssertNoError(t, client.SearchMeta(node, nil, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		if meta.Name != "test1" && meta.Name != "test3" {
+			expected = append(expected, meta.Name)
+		}
+		return nil
+	}))
+	assert.Equal(t, 3, len(expected))
+	t.Run("Match", func(t *testing.T) {
+		// Each file is reported once, in the same order as SearchMeta
+		for i := 0; i < 3; i++ {
+			var names []string
+			testinggo.AssertNoError(t, client.SearchTag(node, spacego.NewTagFilter("y"), func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+				names = append(names, meta.Name)
+				return nil
+			}))
+			assert.Equal(t, expected, names)
+		}
+	})
+	t.Run("NoMatch", func(t *testing.T) {
+		testinggo.AssertNoError(t, client.SearchTag(node, spacego.NewTagFilter("w"), func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			t.Fatalf("Unexpected meta: %s", meta.Name)
+			return nil
+		}))
+	})
+	t.Run("CallbackError", func(t *testing.T) {
+		count := 0
+		err := client.SearchTag(node, spacego.NewTagFilter("x"), func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			count++
+			return errors.New("Stop")
+		})
+		testinggo.AssertError(t, "Stop", err)
+		assert.Equal(t, 1, count)
+	})
+	t.Run("NetworkUnavailable", func(t *testing.T) {
+		// Workers refresh tag channels concurrently, events are reported one at a time
+		var events []*spaceclientgo.Event
+		client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithEventHook(func(e *spaceclientgo.Event) {
+			events = append(events, e)
+		}))
+		node := makeNode(t, "Tester", cache.NewMemory(100), test.NewFailingNetwork())
+		for i := 0; i < 2*spaceclientgo.SEARCH_TAG_WORKERS; i++ {
+			ref, err := client.Add(node, nil, "test"+strconv.Itoa(i), "text/plain", nil)
+			assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+			_, err = client.AddTag(node, nil, ref.RecordHash, []string{"x"})
+			assert.True(t, errors.Is(err, spaceclientgo.ErrNetworkUnavailable))
+		}
+		events = nil
+		count := 0
+		testinggo.AssertNoError(t, client.SearchTag(node, spacego.NewTagFilter("x"), func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			count++
+			return nil
+		}))
+		assert.Equal(t, 2*spaceclientgo.SEARCH_TAG_WORKERS, count)
+		refreshes := 0
+		for _, e := range events {
+			if strings.HasPrefix(e.Channel, spacego.TagChannelName("")) {
+				assert.Equal(t, spaceclientgo.EVENT_REFRESH_FAILED, e.Type)
+				refreshes++
+			}
+		}
+		assert.Equal(t, 2*spaceclientgo.SEARCH_TAG_WORKERS, refreshes)
+	})
 }
 
 func TestClientAddTag(t *testing.T) {
-	// TODO
+	t.Run("Owner", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClient()
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+
+		references, err := client.AddTag(node, nil, ref.RecordHash, []string{"foo", "bar"})
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, 2, len(references))
+
+		var values []string
+		testinggo.AssertNoError(t, client.AllTagsForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+			values = append(values, tag.Value)
+			return nil
+		}))
+		assert.ElementsMatch(t, []string{"foo", "bar"}, values)
+
+		var names []string
+		testinggo.AssertNoError(t, client.SearchTag(node, spacego.NewTagFilter("foo"), func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			names = append(names, meta.Name)
+			return nil
+		}))
+		assert.Equal(t, []string{"test"}, names)
+	})
+	t.Run("Shared", func(t *testing.T) {
+		cache := cache.NewMemory(10)
+		alice := makeNode(t, "Alice", cache, nil)
+		bob := makeNode(t, "Bob", cache, nil)
+		testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+		testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+		client := spaceclientgo.NewSpaceClient()
+		ref, err := client.Add(alice, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		// Tags added before sharing are copied for the recipient
+		_, err = client.AddTag(alice, nil, ref.RecordHash, []string{"before"})
+		testinggo.AssertNoError(t, err)
+		testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+		// Tags added after sharing are readable by the recipient
+		_, err = client.AddTag(alice, nil, ref.RecordHash, []string{"after"})
+		testinggo.AssertNoError(t, err)
+
+		var values []string
+		testinggo.AssertNoError(t, client.AllTagsForHash(bob, ref.RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+			values = append(values, tag.Value)
+			return nil
+		}))
+		assert.ElementsMatch(t, []string{"before", "after"}, values)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClient()
+		_, err := client.AddTag(node, nil, []byte("unknown"), []string{"foo"})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+	})
+	t.Run("Trashed", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClient()
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		testinggo.AssertNoError(t, client.Delete(node, nil, ref.RecordHash))
+		_, err = client.AddTag(node, nil, ref.RecordHash, []string{"foo"})
+		assert.True(t, errors.Is(err, spaceclientgo.ErrFileNotFound))
+		// No tag was written
+		testinggo.AssertNoError(t, client.AllTagsForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+			t.Fatalf("Unexpected tag: %s", tag.Value)
+			return nil
+		}))
+	})
+}
+
+func TestClientRemoveTag(t *testing.T) {
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+	_, err = client.AddTag(node, nil, ref.RecordHash, []string{"foo", "bar", "foo"})
+	testinggo.AssertNoError(t, err)
+
+	references, err := client.RemoveTag(node, nil, ref.RecordHash, []string{"foo", "baz"})
+	testinggo.AssertNoError(t, err)
+	assert.Equal(t, 1, len(references))
+
+	var values []string
+	testinggo.AssertNoError(t, client.AllTagsForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+		values = append(values, tag.Value)
+		return nil
+	}))
+	assert.Equal(t, []string{"bar"}, values)
+
+	var count int
+	testinggo.AssertNoError(t, client.SearchTag(node, spacego.NewTagFilter("foo"), func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 0, count)
+
+	// Tag can be added again after removal
+	_, err = client.AddTag(node, nil, ref.RecordHash, []string{"foo"})
+	testinggo.AssertNoError(t, err)
+	testinggo.AssertNoError(t, client.SearchTag(node, spacego.NewTagFilter("foo"), func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 1, count)
+
+	t.Run("Shared", func(t *testing.T) {
+		alice := makeNode(t, "Alice", cache, nil)
+		bob := makeNode(t, "Bob", cache, nil)
+		testinggo.AssertNoError(t, aliasgo.Register(alice, nil))
+		testinggo.AssertNoError(t, aliasgo.Register(bob, nil))
+		ref, err := client.Add(alice, nil, "test", "text/plain", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+		_, err = client.AddTag(alice, nil, ref.RecordHash, []string{"foo", "bar"})
+		testinggo.AssertNoError(t, err)
+		testinggo.AssertNoError(t, client.Share(alice, nil, ref.RecordHash, "Bob"))
+
+		// Removal reaches the copy of the tag written for the recipient
+		_, err = client.RemoveTag(alice, nil, ref.RecordHash, []string{"foo"})
+		testinggo.AssertNoError(t, err)
+		var values []string
+		testinggo.AssertNoError(t, client.AllTagsForHash(bob, ref.RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+			values = append(values, tag.Value)
+			return nil
+		}))
+		assert.Equal(t, []string{"bar"}, values)
+	})
+}
+
+func TestClientWatchTags(t *testing.T) {
+	peer := test.NewNotifyingPeer(t)
+	cache := cache.NewMemory(10)
+	n := makeNode(t, "Tester", cache, nil)
+	client := spaceclientgo.NewSpaceClientWithOptions(spaceclientgo.WithNotifier(spaceclientgo.NewTCPNotifier(peer.Address())))
+	ref, err := client.Add(n, nil, "test", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+	_, err = client.AddTag(n, nil, ref.RecordHash, []string{"existing"})
+	testinggo.AssertNoError(t, err)
+
+	values := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.WatchTags(ctx, n, ref.RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+		values <- tag.Value
+		return nil
+	})
+	channel := spacego.TagChannelName(base64.RawURLEncoding.EncodeToString(ref.RecordHash))
+	peer.AwaitSubscription(t, channel)
+
+	// Simulate another device using the same account
+	device := node.New(n.Account(), cache, nil)
+	_, err = spaceclientgo.NewSpaceClient().AddTag(device, nil, ref.RecordHash, []string{"foo", "bar"})
+	testinggo.AssertNoError(t, err)
+	tags := spacego.OpenTagChannel(base64.RawURLEncoding.EncodeToString(ref.RecordHash))
+	testinggo.AssertNoError(t, tags.Load(cache, nil))
+	peer.Notify(channel, tags.Head())
+
+	var actual []string
+	for len(actual) < 2 {
+		select {
+		case v := <-values:
+			actual = append(actual, v)
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("Expected tags; got %v", actual)
+		}
+	}
+	assert.Equal(t, []string{"foo", "bar"}, actual)
+
+	// Removals are not reported
+	_, err = client.RemoveTag(n, nil, ref.RecordHash, []string{"foo"})
+	testinggo.AssertNoError(t, err)
+	select {
+	case v := <-values:
+		t.Fatalf("Unexpected tag: %s", v)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestClientPreview(t *testing.T) {
+	t.Run("Text", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClient()
+		var lines []string
+		for i := 0; i < 20; i++ {
+			lines = append(lines, "line "+strconv.Itoa(i))
+		}
+		ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader(strings.Join(lines, "\n")))
+		testinggo.AssertNoError(t, err)
+
+		var previews []*spacego.Preview
+		testinggo.AssertNoError(t, client.AllPreviewsForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+			previews = append(previews, preview)
+			return nil
+		}))
+		assert.Equal(t, 1, len(previews))
+		assert.Equal(t, "text/plain", previews[0].Type)
+		assert.Equal(t, strings.Join(lines[:10], "\n"), string(previews[0].Data))
+	})
+	t.Run("Image", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClient()
+		img := image.NewRGBA(image.Rect(0, 0, 1024, 512))
+		for y := 0; y < 512; y++ {
+			for x := 0; x < 1024; x++ {
+				img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+			}
+		}
+		var buffer bytes.Buffer
+		testinggo.AssertNoError(t, png.Encode(&buffer, img))
+		ref, err := client.Add(node, nil, "test", "image/png", &buffer)
+		testinggo.AssertNoError(t, err)
+
+		var previews []*spacego.Preview
+		testinggo.AssertNoError(t, client.AllPreviewsForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+			previews = append(previews, preview)
+			return nil
+		}))
+		assert.Equal(t, 1, len(previews))
+		preview := previews[0]
+		assert.Equal(t, "image/png", preview.Type)
+		assert.Equal(t, uint32(256), preview.Width)
+		assert.Equal(t, uint32(128), preview.Height)
+		decoded, err := png.Decode(bytes.NewReader(preview.Data))
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 256, 128), decoded.Bounds())
+	})
+	t.Run("ImageTooLarge", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClient()
+		// PNG header claiming 100000x100000 pixels, which must not be decoded
+		var buffer bytes.Buffer
+		buffer.WriteString("\x89PNG\r\n\x1a\n")
+		header := make([]byte, 13)
+		binary.BigEndian.PutUint32(header[0:], 100000)
+		binary.BigEndian.PutUint32(header[4:], 100000)
+		header[8] = 8 // Bit depth
+		header[9] = 2 // Truecolor
+		chunk := append([]byte("IHDR"), header...)
+		binary.Write(&buffer, binary.BigEndian, uint32(len(header)))
+		buffer.Write(chunk)
+		binary.Write(&buffer, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+		ref, err := client.Add(node, nil, "test", "image/png", &buffer)
+		testinggo.AssertNoError(t, err)
+
+		var count int
+		testinggo.AssertNoError(t, client.AllPreviewsForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+			count++
+			return nil
+		}))
+		assert.Equal(t, 0, count)
+	})
+	t.Run("Unsupported", func(t *testing.T) {
+		alias := "Tester"
+		cache := cache.NewMemory(10)
+		node := makeNode(t, alias, cache, nil)
+		client := spaceclientgo.NewSpaceClient()
+		ref, err := client.Add(node, nil, "test", "application/octet-stream", strings.NewReader("testing"))
+		testinggo.AssertNoError(t, err)
+
+		var count int
+		testinggo.AssertNoError(t, client.AllPreviewsForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+			count++
+			return nil
+		}))
+		assert.Equal(t, 0, count)
+	})
 }
 
 func TestClientAllTagsForHash(t *testing.T) {
-	// TODO
+	alias := "Tester"
+	cache := cache.NewMemory(10)
+	node := makeNode(t, alias, cache, nil)
+	client := spaceclientgo.NewSpaceClient()
+	ref, err := client.Add(node, nil, "test", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+	other, err := client.Add(node, nil, "other", "text/plain", strings.NewReader("testing"))
+	testinggo.AssertNoError(t, err)
+	_, err = client.AddTag(node, nil, other.RecordHash, []string{"other"})
+	testinggo.AssertNoError(t, err)
+
+	allTags := func() []string {
+		t.Helper()
+		var values []string
+		testinggo.AssertNoError(t, client.AllTagsForHash(node, ref.RecordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+			values = append(values, tag.Value)
+			return nil
+		}))
+		return values
+	}
+
+	assert.Equal(t, 0, len(allTags()))
+
+	_, err = client.AddTag(node, nil, ref.RecordHash, []string{"foo", "bar"})
+	testinggo.AssertNoError(t, err)
+	assert.ElementsMatch(t, []string{"foo", "bar"}, allTags())
+
+	// Removed tag is not listed
+	_, err = client.RemoveTag(node, nil, ref.RecordHash, []string{"foo"})
+	testinggo.AssertNoError(t, err)
+	assert.Equal(t, []string{"bar"}, allTags())
+
+	// Re-added tag is listed once
+	_, err = client.AddTag(node, nil, ref.RecordHash, []string{"foo"})
+	testinggo.AssertNoError(t, err)
+	assert.ElementsMatch(t, []string{"foo", "bar"}, allTags())
 }
 
 func TestClientRegistration(t *testing.T) {
@@ -23,18 +23,34 @@ import (
 	"aletheiaware.com/financego"
 	"aletheiaware.com/spaceclientgo"
 	"aletheiaware.com/spacego"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"io/ioutil"
 	"log"
+	"math"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
-var peer = flag.String("peer", "", "Space peer")
+var (
+	peer         = flag.String("peer", "", "Space peer")
+	compression  = flag.String("compression", "", "Compression applied to added files (gzip or zstd)")
+	contentCache = flag.Uint64("content-cache", 0, "Size in bytes of the cache of decrypted file content kept in the root directory, zero disables")
+	timeout      = flag.Duration("timeout", 0, "Time allowed for the command to complete, zero waits indefinitely, mining may overrun it")
+)
 
 func main() {
 	// Parse command line flags
@@ -43,7 +59,19 @@ func main() {
 	// Set log flags
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
-	client := spaceclientgo.NewSpaceClient(bcgo.SplitRemoveEmpty(*peer, ",")...)
+	client := spaceclientgo.NewSpaceClientWithOptions(
+		spaceclientgo.WithPeers(bcgo.SplitRemoveEmpty(*peer, ",")...),
+		spaceclientgo.WithCompression(*compression),
+		// Cache file content in root directory so it persists between commands
+		spaceclientgo.WithFileSystemContentCache(*contentCache),
+	)
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
 
 	args := flag.Args()
 
@@ -53,45 +81,37 @@ func main() {
 			PrintLegalese(os.Stdout)
 			root, err := client.Root()
 			if err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			// Add Space hosts to peers
 			for _, host := range spacego.SpaceHosts() {
 				if err := bcgo.AddPeer(root, host); err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 			}
 			// Add BC host to peers
 			if err := bcgo.AddPeer(root, bcgo.BCHost()); err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			node, err := client.Node()
 			if err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			if err := aliasgo.Register(node, &bcgo.PrintingMiningListener{Output: os.Stdout}); err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			if err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			log.Println("Initialized")
 			if err := bcclientgo.PrintIdentity(os.Stdout, node.Account()); err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 		case "add":
 			if len(args) > 2 {
 				node, err := client.Node()
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				name := args[1]
 				mime := args[2]
@@ -101,120 +121,252 @@ func main() {
 					// Read data from file
 					file, err := os.Open(args[3])
 					if err != nil {
-						log.Println(err)
-						return
+						exit(err)
 					}
 					reader = file
 				} else {
 					log.Println("Reading from stdin, use CTRL-D to terminate")
 				}
-				reference, err := client.Add(node, &bcgo.PrintingMiningListener{Output: os.Stdout}, name, mime, reader)
+				reference, err := client.AddContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, name, mime, reader)
+				if reference != nil {
+					log.Println("Mined metadata", base64.RawURLEncoding.EncodeToString(reference.RecordHash))
+				}
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
-				log.Println("Mined metadata", base64.RawURLEncoding.EncodeToString(reference.RecordHash))
 			} else {
 				log.Println("add <name> <mime> <file>")
 				log.Println("add <name> <mime> (data read from stdin)")
 			}
+		case "add-directory":
+			tag := false
+			skip := true
+			var dirs []string
+			for _, a := range args[1:] {
+				switch a {
+				case "-tag", "--tag":
+					tag = true
+				case "-no-skip", "--no-skip":
+					skip = false
+				default:
+					dirs = append(dirs, a)
+				}
+			}
+			if len(dirs) > 0 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				added, skipped, failed, err := addDirectories(ctx, client, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, dirs, tag, skip)
+				log.Println("Added", added, "files, skipped", skipped, "files already uploaded, failed", failed, "files")
+				if err != nil {
+					exit(err)
+				}
+			} else {
+				log.Println("add-directory <directory>... (add all files in directories, skipping those already uploaded)")
+				log.Println("add-directory --tag <directory>... (add all files in directories, tagging each with the directories leading to it)")
+				log.Println("add-directory --no-skip <directory>... (add all files in directories, including those already uploaded)")
+			}
 		case "list":
 			var mimes []string
-			if len(args) > 1 {
-				mimes = args[1:]
+			shared := false
+			for _, a := range args[1:] {
+				switch a {
+				case "-shared", "--shared":
+					shared = true
+				default:
+					mimes = append(mimes, a)
+				}
 			}
 			count := 0
-			callback := func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			filter := func(meta *spacego.Meta) bool {
 				success := len(mimes) == 0
 				for _, m := range mimes {
 					if meta.Type == m {
 						success = true
 					}
 				}
-				if !success {
-					return nil
-				}
-				count += 1
-				return PrintMeta(os.Stdout, entry, meta)
+				return success
 			}
 
 			node, err := client.Node()
 			if err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 
-			log.Println("Files:")
-			if err := client.AllMetas(node, callback); err != nil {
-				log.Println(err)
-				return
+			if shared {
+				log.Println("Shared Files:")
+				if err := client.SharedMetasContext(ctx, node, func(owner string, entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+					if !filter(meta) {
+						return nil
+					}
+					count += 1
+					return PrintSharedMeta(os.Stdout, owner, entry, meta)
+				}); err != nil {
+					exit(err)
+				}
+			} else {
+				log.Println("Files:")
+				if err := client.AllMetasContext(ctx, node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+					if !filter(meta) {
+						return nil
+					}
+					count += 1
+					return PrintMeta(os.Stdout, entry, meta)
+				}); err != nil {
+					exit(err)
+				}
 			}
 			log.Println(count, "files")
 		case "show":
 			if len(args) > 1 {
 				node, err := client.Node()
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
-				if err := client.MetaForHash(node, recordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+				if err := client.MetaForHashContext(ctx, node, recordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
 					return PrintMeta(os.Stdout, entry, meta)
 				}); err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 			} else {
 				log.Println("show <file-hash>")
 			}
+		case "mv", "retype":
+			if len(args) > 2 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
+				if err != nil {
+					exit(err)
+				}
+				var name, mime string
+				if args[0] == "mv" {
+					name = args[2]
+				} else {
+					mime = args[2]
+				}
+				if err := client.UpdateMetaContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash, name, mime); err != nil {
+					exit(err)
+				}
+				if err := client.MetaForHashContext(ctx, node, recordHash, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+					return PrintMeta(os.Stdout, entry, meta)
+				}); err != nil {
+					exit(err)
+				}
+			} else if args[0] == "mv" {
+				log.Println("mv <hash> <name> (rename file)")
+			} else {
+				log.Println("retype <hash> <mime> (change MIME type of file)")
+			}
+		case "preview":
+			if len(args) > 1 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
+				if err != nil {
+					exit(err)
+				}
+				count := 0
+				if err := client.AllPreviewsForHashContext(ctx, node, recordHash, func(entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+					count += 1
+					if len(args) > 2 {
+						if count > 1 {
+							// Only write first preview
+							return nil
+						}
+						log.Println("Writing to " + args[2])
+						return ioutil.WriteFile(args[2], preview.Data, os.ModePerm)
+					}
+					return PrintPreview(os.Stdout, entry, preview)
+				}); err != nil {
+					exit(err)
+				}
+				log.Println(count, "previews")
+			} else {
+				log.Println("preview <hash> (display previews of file)")
+				log.Println("preview <hash> <file> (write preview of file to file)")
+			}
 		case "get":
+			byteRange, args, ranged := option(args, "range")
+			at, args, versioned := option(args, "at")
 			if len(args) > 1 {
 				node, err := client.Node()
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				writer := os.Stdout
 				if len(args) > 2 {
 					log.Println("Writing to " + args[2])
 					writer, err = os.OpenFile(args[2], os.O_CREATE|os.O_WRONLY, os.ModePerm)
 					if err != nil {
-						log.Println(err)
-						return
+						exit(err)
 					}
 				}
-				reader, err := client.ReadFile(node, recordHash)
-				if err != nil {
-					log.Println(err)
-					return
+				var reader io.Reader
+				switch {
+				case ranged && versioned:
+					exit(errors.New("Cannot use --range with --at"))
+				case ranged:
+					offset, length, err := parseRange(byteRange)
+					if err != nil {
+						exit(err)
+					}
+					reader, err = client.ReadFileRangeContext(ctx, node, recordHash, offset, length)
+					if err != nil {
+						exit(err)
+					}
+				case versioned:
+					timestamp, version, err := parseAt(at)
+					if err != nil {
+						exit(err)
+					}
+					if version != nil {
+						reader, err = client.ReadFileVersionContext(ctx, node, recordHash, version)
+					} else {
+						reader, err = client.ReadFileAtContext(ctx, node, recordHash, timestamp)
+					}
+					if err != nil {
+						exit(err)
+					}
+				default:
+					file, err := client.OpenFileContext(ctx, node, recordHash)
+					if err != nil {
+						exit(err)
+					}
+					defer file.Close()
+					reader = file
 				}
 				count, err := io.Copy(writer, reader)
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				log.Println("Wrote", bcgo.BinarySizeToString(uint64(count)))
 			} else {
 				log.Println("get <hash> <file>")
 				log.Println("get <hash> (write to stdout)")
+				log.Println("get <hash> [<file>] --range <start>-<end> (write given byte range)")
+				log.Println("get <hash> [<file>] --at <time|record> (write file as it was at given time or delta record)")
 			}
 		case "get-all":
 			if len(args) > 1 {
 				node, err := client.Node()
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
-				if err := client.AllMetas(node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+				if err := client.AllMetasContext(ctx, node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
 					hash := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
 					dir := filepath.Join(args[1], hash)
 					if err := os.MkdirAll(dir, os.ModePerm); err != nil {
@@ -231,10 +383,11 @@ func main() {
 						if err != nil {
 							return err
 						}
-						reader, err := client.ReadFile(node, entry.RecordHash)
+						reader, err := client.OpenFileContext(ctx, node, entry.RecordHash)
 						if err != nil {
 							return err
 						}
+						defer reader.Close()
 						count, err := io.Copy(writer, reader)
 						if err != nil {
 							return err
@@ -243,52 +396,145 @@ func main() {
 					}
 					return nil
 				}); err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 			} else {
 				log.Println("get-all <directory>")
 			}
+		case "log":
+			if len(args) > 1 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
+				if err != nil {
+					exit(err)
+				}
+				var deltas []string
+				var inserted, deleted uint64
+				if err := client.HistoryContext(ctx, node, recordHash, func(block []byte, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+					// Print most recent first
+					output := &strings.Builder{}
+					if err := PrintDelta(output, block, entry, delta); err != nil {
+						return err
+					}
+					deltas = append([]string{output.String()}, deltas...)
+					inserted += uint64(len(delta.Insert))
+					deleted += delta.Delete
+					return nil
+				}); err != nil {
+					exit(err)
+				}
+				for _, d := range deltas {
+					fmt.Print(d)
+				}
+				log.Println(len(deltas), "deltas,", bcgo.BinarySizeToString(inserted), "inserted,", bcgo.BinarySizeToString(deleted), "deleted")
+			} else {
+				log.Println("log <hash> (display history of file)")
+			}
+		case "diff":
+			if len(args) > 1 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
+				if err != nil {
+					exit(err)
+				}
+				var from, to []byte
+				if len(args) > 2 {
+					from, err = base64.RawURLEncoding.DecodeString(args[2])
+					if err != nil {
+						exit(err)
+					}
+					if len(args) > 3 {
+						to, err = base64.RawURLEncoding.DecodeString(args[3])
+						if err != nil {
+							exit(err)
+						}
+					}
+				} else {
+					// Diff the latest amendment
+					var records [][]byte
+					if err := client.HistoryContext(ctx, node, recordHash, func(block []byte, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+						records = append(records, entry.RecordHash)
+						return nil
+					}); err != nil {
+						exit(err)
+					}
+					if len(records) > 1 {
+						from = records[len(records)-2]
+					}
+				}
+				reader, err := client.DiffContext(ctx, node, recordHash, from, to)
+				if err != nil {
+					exit(err)
+				}
+				if _, err := io.Copy(os.Stdout, reader); err != nil {
+					exit(err)
+				}
+			} else {
+				log.Println("diff <hash> (display latest change to file)")
+				log.Println("diff <hash> <from> (display changes to file since given delta record)")
+				log.Println("diff <hash> <from> <to> (display changes to file between given delta records)")
+			}
 		case "set":
 			if len(args) > 1 {
 				node, err := client.Node()
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				reader := os.Stdin
 				if len(args) > 2 {
 					log.Println("Reading from " + args[2])
 					reader, err = os.Open(args[2])
 					if err != nil {
-						log.Println(err)
-						return
+						exit(err)
 					}
 				}
-				writer, err := client.WriteFile(node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash)
+				writer, err := client.WriteFileContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash)
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				count, err := io.Copy(writer, reader)
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				if err := writer.Close(); err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				log.Println("Wrote", bcgo.BinarySizeToString(uint64(count)))
 			} else {
 				log.Println("set <hash> <file>")
 				log.Println("set <hash> (read from stdin)")
 			}
+		case "revert":
+			if len(args) > 2 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
+				if err != nil {
+					exit(err)
+				}
+				version, err := base64.RawURLEncoding.DecodeString(args[2])
+				if err != nil {
+					exit(err)
+				}
+				if err := client.RevertContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash, version); err != nil {
+					exit(err)
+				}
+				log.Println("Reverted", args[1], "to", args[2])
+			} else {
+				log.Println("revert <hash> <record> (revert file to version after given delta record)")
+			}
 		case "search":
 			// search files by name, type, and/or tag
 			if len(args) > 1 {
@@ -307,8 +553,7 @@ func main() {
 				}
 				node, err := client.Node()
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				log.Println("Files:")
 				var hashes []string
@@ -331,23 +576,20 @@ func main() {
 				}
 				// Search by name
 				if len(names) > 0 {
-					if client.SearchMeta(node, spacego.NewNameFilter(names...), callback); err != nil {
-						log.Println(err)
-						return
+					if err := client.SearchMetaContext(ctx, node, spacego.NewNameFilter(names...), callback); err != nil {
+						exit(err)
 					}
 				}
 				// Search by type
 				if len(types) > 0 {
-					if client.SearchMeta(node, spacego.NewTypeFilter(types...), callback); err != nil {
-						log.Println(err)
-						return
+					if err := client.SearchMetaContext(ctx, node, spacego.NewTypeFilter(types...), callback); err != nil {
+						exit(err)
 					}
 				}
 				// Search by tag
 				if len(tags) > 0 {
-					if client.SearchTag(node, spacego.NewTagFilter(tags...), callback); err != nil {
-						log.Println(err)
-						return
+					if err := client.SearchTagContext(ctx, node, spacego.NewTagFilter(tags...), callback); err != nil {
+						exit(err)
 					}
 				}
 				// Sort by timestamp
@@ -372,50 +614,169 @@ func main() {
 			if len(args) > 1 {
 				node, err := client.Node()
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
 				if err != nil {
-					log.Println(err)
-					return
+					exit(err)
 				}
 				if len(args) > 2 {
 					tags := args[2:]
 
-					references, err := client.AddTag(node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash, tags)
+					references, err := client.AddTagContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash, tags)
+					if references != nil {
+						log.Println("Tagged", args[1], references)
+					}
 					if err != nil {
-						log.Println(err)
-						return
+						exit(err)
 					}
-
-					log.Println("Tagged", args[1], references)
 				} else {
-					if err := client.AllTagsForHash(node, recordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+					if err := client.AllTagsForHashContext(ctx, node, recordHash, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
 						log.Println(tag.Value)
 						return nil
 					}); err != nil {
-						log.Println(err)
-						return
+						exit(err)
 					}
 				}
 			} else {
 				log.Println("tag <hash> (display file tags)")
 				log.Println("tag <hash> <tag>... (tag file with the given tags)")
 			}
+		case "untag":
+			if len(args) > 2 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
+				if err != nil {
+					exit(err)
+				}
+				tags := args[2:]
+
+				references, err := client.RemoveTagContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash, tags)
+				if references != nil {
+					log.Println("Untagged", args[1], references)
+				}
+				if err != nil {
+					exit(err)
+				}
+			} else {
+				log.Println("untag <hash> <tag>... (remove the given tags from file)")
+			}
+		case "share":
+			if len(args) > 2 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
+				if err != nil {
+					exit(err)
+				}
+				aliases := args[2:]
+				if err := client.ShareContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash, aliases...); err != nil {
+					exit(err)
+				}
+				log.Println("Shared", args[1], "with", strings.Join(aliases, ", "))
+			} else {
+				log.Println("share <hash> <alias>... (grant the given aliases access to file)")
+			}
+		case "revoke":
+			if len(args) > 2 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				recordHash, err := base64.RawURLEncoding.DecodeString(args[1])
+				if err != nil {
+					exit(err)
+				}
+				aliases := args[2:]
+				if err := client.RevokeContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash, aliases...); err != nil {
+					exit(err)
+				}
+				log.Println("Revoked", strings.Join(aliases, ", "), "from", args[1])
+			} else {
+				log.Println("revoke <hash> <alias>... (remove the given aliases' access to file)")
+			}
+		case "rm":
+			if len(args) > 1 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				for _, a := range args[1:] {
+					recordHash, err := base64.RawURLEncoding.DecodeString(a)
+					if err != nil {
+						exit(err)
+					}
+					if err := client.DeleteContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash); err != nil {
+						exit(err)
+					}
+					log.Println("Moved", a, "to trash")
+				}
+			} else {
+				log.Println("rm <hash>... (move files to trash)")
+			}
+		case "restore":
+			if len(args) > 1 {
+				node, err := client.Node()
+				if err != nil {
+					exit(err)
+				}
+				for _, a := range args[1:] {
+					recordHash, err := base64.RawURLEncoding.DecodeString(a)
+					if err != nil {
+						exit(err)
+					}
+					if err := client.RestoreContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}, recordHash); err != nil {
+						exit(err)
+					}
+					log.Println("Restored", a)
+				}
+			} else {
+				log.Println("restore <hash>... (restore files from trash)")
+			}
+		case "trash":
+			node, err := client.Node()
+			if err != nil {
+				exit(err)
+			}
+			if len(args) > 1 {
+				switch args[1] {
+				case "-empty", "--empty":
+					if err := client.EmptyTrashContext(ctx, node, &bcgo.PrintingMiningListener{Output: os.Stdout}); err != nil {
+						exit(err)
+					}
+					log.Println("Emptied trash")
+				default:
+					log.Println("trash (display files in trash)")
+					log.Println("trash --empty (permanently remove files in trash)")
+				}
+				return
+			}
+			log.Println("Trash:")
+			count := 0
+			if err := client.TrashContext(ctx, node, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+				count += 1
+				return PrintMeta(os.Stdout, entry, meta)
+			}); err != nil {
+				exit(err)
+			}
+			log.Println(count, "files")
 		case "registration":
 			merchant := ""
 			if len(args) > 1 {
 				merchant = args[1]
 			}
 			count := 0
-			if err := client.Registration(merchant, func(e *bcgo.BlockEntry, r *financego.Registration) error {
+			if err := client.RegistrationContext(ctx, merchant, func(e *bcgo.BlockEntry, r *financego.Registration) error {
 				log.Println(r)
 				count++
 				return nil
 			}); err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			log.Println(count, "results")
 		case "subscription":
@@ -424,20 +785,18 @@ func main() {
 				merchant = args[1]
 			}
 			count := 0
-			if err := client.Subscription(merchant, func(e *bcgo.BlockEntry, s *financego.Subscription) error {
+			if err := client.SubscriptionContext(ctx, merchant, func(e *bcgo.BlockEntry, s *financego.Subscription) error {
 				log.Println(s)
 				count++
 				return nil
 			}); err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			log.Println(count, "results")
 		case "registrars":
 			node, err := client.Node()
 			if err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			count := 0
 			if err := spacego.AllRegistrarsForNode(node, func(a *spacego.Registrar, r *financego.Registration, s *financego.Subscription) error {
@@ -445,8 +804,7 @@ func main() {
 				count++
 				return nil
 			}); err != nil {
-				log.Println(err)
-				return
+				exit(err)
 			}
 			log.Println(count, "results")
 		default:
@@ -457,27 +815,79 @@ func main() {
 	}
 }
 
+// Exit codes identifying the kind of error which ended a command.
+const (
+	EXIT_ERROR               = 1
+	EXIT_FILE_NOT_FOUND      = 3
+	EXIT_ACCESS_DENIED       = 4
+	EXIT_NETWORK_UNAVAILABLE = 5
+	EXIT_TIMEOUT             = 6
+	EXIT_NOT_DECRYPTABLE     = 8
+)
+
+// exit logs the given error and exits with the code identifying its kind.
+func exit(err error) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, spaceclientgo.ErrFileNotFound), errors.Is(err, spaceclientgo.ErrVersionNotFound):
+		os.Exit(EXIT_FILE_NOT_FOUND)
+	case errors.Is(err, spaceclientgo.ErrAccessDenied):
+		os.Exit(EXIT_ACCESS_DENIED)
+	case errors.Is(err, spaceclientgo.ErrNetworkUnavailable):
+		os.Exit(EXIT_NETWORK_UNAVAILABLE)
+	case errors.Is(err, spaceclientgo.ErrNotDecryptable):
+		os.Exit(EXIT_NOT_DECRYPTABLE)
+	case errors.Is(err, context.DeadlineExceeded):
+		os.Exit(EXIT_TIMEOUT)
+	}
+	os.Exit(EXIT_ERROR)
+}
+
 func PrintUsage(output io.Writer) {
 	fmt.Fprintln(output, "Space Usage:")
 	fmt.Fprintln(output, "\tspace - display usage")
 	fmt.Fprintln(output, "\tspace init - initializes environment, generates key pair, and registers alias")
+	fmt.Fprintln(output, "\tspace -timeout [duration] [command] - run command, giving up once given duration (e.g. 30s) has elapsed, though mining is not interrupted")
 	fmt.Fprintln(output)
 	fmt.Fprintln(output, "\tspace add [name] [type] - read stdin and mine a new record into blockchain")
 	fmt.Fprintln(output, "\tspace add [name] [type] [file] - read file and mine a new record into blockchain")
-	// TODO fmt.Fprintln(output, "\tspace add-directory [directory] - read all files in directory and mine new records into blockchain")
+	fmt.Fprintln(output, "\tspace -compression gzip add [name] [type] [file] - compress file and mine a new record into blockchain")
+	fmt.Fprintln(output, "\tspace -compression zstd add [name] [type] [file] - compress file with zstd and mine a new record into blockchain")
+	fmt.Fprintln(output, "\tspace add-directory [directory...] - read all files in directories and mine new records into blockchain, skipping files whose content was already uploaded by add-directory")
+	fmt.Fprintln(output, "\tspace add-directory --tag [directory...] - read all files in directories and mine new records into blockchain, tagging each file with the directories leading to it")
+	fmt.Fprintln(output, "\tspace add-directory --no-skip [directory...] - read all files in directories and mine new records into blockchain, including files whose content was already uploaded")
 	fmt.Fprintln(output)
 	fmt.Fprintln(output, "\tspace list - prints all files created by this key")
 	fmt.Fprintln(output, "\tspace list [type] - display metadata of all files with given MIME type")
+	fmt.Fprintln(output, "\tspace list --shared - display metadata of all files shared with this key")
 	fmt.Fprintln(output, "\tspace show [hash] - display metadata of file with given hash")
+	fmt.Fprintln(output, "\tspace mv [hash] [name] - rename file with given hash")
+	fmt.Fprintln(output, "\tspace retype [hash] [type] - change MIME type of file with given hash")
 	// TODO fmt.Fprintln(output, "\tspace show-keys [hash] - display keys of file with given hash")
+	fmt.Fprintln(output, "\tspace preview [hash] - display previews of file with given hash")
+	fmt.Fprintln(output, "\tspace preview [hash] [file] - write preview of file with given hash to file")
 	fmt.Fprintln(output, "\tspace get [hash] - write file with given hash to stdout")
 	fmt.Fprintln(output, "\tspace get [hash] [file] - write file with given hash to file")
+	fmt.Fprintln(output, "\tspace get [hash] --range [start]-[end] - write given inclusive byte range of file with given hash to stdout")
+	fmt.Fprintln(output, "\tspace get [hash] --at [time|record] - write file with given hash as it was at given time (RFC3339 or nanoseconds) or delta record hash to stdout")
 	fmt.Fprintln(output, "\tspace get-all [directory] - write all files to given directory")
+	fmt.Fprintln(output, "\tspace log [hash] - display history of file with given hash")
+	fmt.Fprintln(output, "\tspace diff [hash] [from] [to] - display changes to file with given hash between given delta records")
 	fmt.Fprintln(output)
 	fmt.Fprintln(output, "\tspace set [hash] - write stdin to file with given hash")
 	fmt.Fprintln(output, "\tspace set [hash] [file] - write file to file with given hash")
+	fmt.Fprintln(output, "\tspace revert [hash] [record] - revert file with given hash to version after given delta record")
 	fmt.Fprintln(output)
 	fmt.Fprintln(output, "\tspace tag [hash] [tag...] - tags file with given hash with given tags")
+	fmt.Fprintln(output, "\tspace untag [hash] [tag...] - removes given tags from file with given hash")
+	fmt.Fprintln(output)
+	fmt.Fprintln(output, "\tspace rm [hash...] - move files with given hashes to trash")
+	fmt.Fprintln(output, "\tspace trash - display metadata of all files in trash")
+	fmt.Fprintln(output, "\tspace trash --empty - permanently remove all files in trash")
+	fmt.Fprintln(output, "\tspace restore [hash...] - restore files with given hashes from trash")
+	fmt.Fprintln(output)
+	fmt.Fprintln(output, "\tspace share [hash] [alias...] - grants the given aliases access to file with given hash")
+	fmt.Fprintln(output, "\tspace revoke [hash] [alias...] - removes the given aliases' access to file with given hash")
 	fmt.Fprintln(output)
 	fmt.Fprintln(output, "\tspace search name:[name] - search files for given name")
 	fmt.Fprintln(output, "\tspace search type:[type] - search files for given type")
@@ -503,6 +913,273 @@ func PrintMeta(output io.Writer, entry *bcgo.BlockEntry, meta *spacego.Meta) err
 	return nil
 }
 
+func PrintSharedMeta(output io.Writer, owner string, entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+	hash := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
+	timestamp := bcgo.TimestampToString(entry.Record.Timestamp)
+	fmt.Fprintf(output, "%s %s %s %s %s\n", hash, timestamp, owner, meta.Name, meta.Type)
+	return nil
+}
+
+func PrintPreview(output io.Writer, entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+	hash := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
+	timestamp := bcgo.TimestampToString(entry.Record.Timestamp)
+	fmt.Fprintf(output, "%s %s %s %dx%d %s\n", hash, timestamp, preview.Type, preview.Width, preview.Height, bcgo.BinarySizeToString(uint64(len(preview.Data))))
+	if strings.HasPrefix(preview.Type, "text/") {
+		fmt.Fprintln(output, string(preview.Data))
+	}
+	return nil
+}
+
+func PrintDelta(output io.Writer, block []byte, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+	fmt.Fprintf(output, "delta %s\n", base64.RawURLEncoding.EncodeToString(entry.RecordHash))
+	fmt.Fprintf(output, "Author: %s\n", entry.Record.Creator)
+	fmt.Fprintf(output, "Date:   %s\n", bcgo.TimestampToString(entry.Record.Timestamp))
+	fmt.Fprintf(output, "Block:  %s\n", base64.RawURLEncoding.EncodeToString(block))
+	fmt.Fprintln(output)
+	fmt.Fprintf(output, "    @%d +%d -%d\n", delta.Offset, len(delta.Insert), delta.Delete)
+	fmt.Fprintln(output)
+	return nil
+}
+
+// option removes the option with the given name, and its value, from the given arguments.
+func option(args []string, name string) (string, []string, bool) {
+	for i, a := range args {
+		for _, prefix := range []string{"-", "--"} {
+			flag := prefix + name
+			if a == flag && i+1 < len(args) {
+				return args[i+1], append(append([]string{}, args[:i]...), args[i+2:]...), true
+			}
+			if strings.HasPrefix(a, flag+"=") {
+				return strings.TrimPrefix(a, flag+"="), append(append([]string{}, args[:i]...), args[i+1:]...), true
+			}
+		}
+	}
+	return "", args, false
+}
+
+// parseRange parses an inclusive byte range of the form start-end, or start- to read until the end of the file.
+func parseRange(r string) (int64, int64, error) {
+	parts := strings.SplitN(r, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Invalid range: %s", r)
+	}
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if parts[1] == "" {
+		return start, math.MaxInt64, nil
+	}
+	end, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("Invalid range: %s", r)
+	}
+	return start, end - start + 1, nil
+}
+
+// parseAt parses a point in the history of a file as either a timestamp, in nanoseconds or RFC3339, or the base64 encoded hash of a delta record.
+func parseAt(at string) (uint64, []byte, error) {
+	if timestamp, err := strconv.ParseUint(at, 10, 64); err == nil {
+		return timestamp, nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, at); err == nil {
+		return uint64(t.UnixNano()), nil, nil
+	}
+	recordHash, err := base64.RawURLEncoding.DecodeString(at)
+	if err != nil || len(recordHash) == 0 {
+		return 0, nil, fmt.Errorf("Invalid time or record: %s", at)
+	}
+	return 0, recordHash, nil
+}
+
+// getMime returns the MIME type of the given file, from its extension or else its content.
+func getMime(file *os.File) (string, error) {
+	t := mime.TypeByExtension(filepath.Ext(file.Name()))
+	if t == "" {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		buffer := make([]byte, 512)
+		n, err := io.ReadFull(file, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		t = http.DetectContentType(buffer[:n])
+	}
+	m, _, err := mime.ParseMediaType(t)
+	return m, err
+}
+
+// ADD_DIRECTORY_BATCH is the most files add-directory adds with each call to AddAll, bounding the number of files held open at once.
+const ADD_DIRECTORY_BATCH = 64
+
+// addDirectories adds all regular files in the given directories with AddAll, recording the hash of the content of each.
+// Files whose content was already uploaded, by an earlier call or another file in the directories, are skipped if skip is true.
+// Files are tagged with the directories leading to them if tag is true.
+// Returns the number of files added, skipped, and which could not be added, and the last error.
+func addDirectories(ctx context.Context, client spaceclientgo.SpaceClient, node bcgo.Node, listener bcgo.MiningListener, dirs []string, tag, skip bool) (added, skipped, failed int, failure error) {
+	// Collect the hashes of the content already uploaded
+	uploaded := make(map[string]bool)
+	if skip {
+		if err := client.ContentHashesContext(ctx, node, func(entry *bcgo.BlockEntry, meta *spacego.Meta, hash string) error {
+			uploaded[hash] = true
+			return nil
+		}); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	var (
+		paths []string
+		files []*os.File
+		specs []spaceclientgo.FileSpec
+		tags  [][]string
+	)
+	// add adds the batch of files, returning an error only if adding should stop
+	add := func() error {
+		defer func() {
+			for _, f := range files {
+				f.Close()
+			}
+			paths, files, specs, tags = nil, nil, nil, nil
+		}()
+		if len(specs) == 0 {
+			return nil
+		}
+		references, err := client.AddAllContext(ctx, node, listener, specs)
+		if references == nil {
+			log.Println(err)
+			failed += len(specs)
+			failure = err
+			return ctx.Err()
+		}
+		added += len(references)
+		for i, reference := range references {
+			log.Println("Mined metadata", paths[i], base64.RawURLEncoding.EncodeToString(reference.RecordHash))
+		}
+		if err != nil {
+			// Files were added, but the content of some was not written or not pushed to peers
+			log.Println(err)
+			failure = err
+			if ctx.Err() != nil {
+				return err
+			}
+		}
+		for i, reference := range references {
+			if len(tags[i]) == 0 {
+				continue
+			}
+			// File was added, so a failure to tag it is reported but not counted as a failure to add it
+			if _, err := client.AddTagContext(ctx, node, listener, reference.RecordHash, tags[i]); err != nil {
+				log.Println(paths[i], "Could not tag:", err)
+				failure = err
+			}
+		}
+		return nil
+	}
+	for _, dir := range dirs {
+		if err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				log.Println(path, err)
+				failed++
+				failure = err
+				return nil
+			}
+			spec, err := fileSpec(file)
+			if err != nil {
+				file.Close()
+				log.Println(path, err)
+				failed++
+				failure = err
+				return nil
+			}
+			if skip && uploaded[spec.Hash] {
+				file.Close()
+				log.Println("Skipping", path)
+				skipped++
+				return nil
+			}
+			uploaded[spec.Hash] = true
+			var t []string
+			if tag {
+				relative, err := filepath.Rel(dir, filepath.Dir(path))
+				if err != nil {
+					file.Close()
+					return err
+				}
+				if relative != "." {
+					t = bcgo.SplitRemoveEmpty(filepath.ToSlash(relative), "/")
+				}
+			}
+			log.Println("Adding", path, spec.Mime)
+			paths = append(paths, path)
+			files = append(files, file)
+			specs = append(specs, spec)
+			tags = append(tags, t)
+			if len(specs) < ADD_DIRECTORY_BATCH {
+				return nil
+			}
+			return add()
+		}); err != nil {
+			add()
+			return added, skipped, failed, err
+		}
+	}
+	if err := add(); err != nil {
+		return added, skipped, failed, err
+	}
+	return added, skipped, failed, failure
+}
+
+// fileSpec returns the spec to add the given file with AddAll, including the hash of its content.
+// The file is read from the start when added.
+func fileSpec(file *os.File) (spaceclientgo.FileSpec, error) {
+	var spec spaceclientgo.FileSpec
+	hash, err := hashContent(file)
+	if err != nil {
+		return spec, err
+	}
+	mime, err := getMime(file)
+	if err != nil {
+		return spec, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return spec, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return spec, err
+	}
+	spec.Name = filepath.Base(file.Name())
+	spec.Mime = mime
+	spec.Hash = hash
+	if info.Size() > 0 {
+		spec.Reader = file
+	}
+	return spec, nil
+}
+
+// hashContent returns the hex encoded SHA-256 hash of the content read from the given reader.
+func hashContent(reader io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 func getExtension(mime string) (string, error) {
 	switch mime {
 	case spacego.MIME_TYPE_IMAGE_JPG, spacego.MIME_TYPE_IMAGE_JPEG:
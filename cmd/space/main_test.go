@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/bcgo/account"
+	"aletheiaware.com/bcgo/cache"
+	"aletheiaware.com/bcgo/node"
+	"aletheiaware.com/spaceclientgo"
+	"aletheiaware.com/spacego"
+	"aletheiaware.com/testinggo"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeDirectory(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		testinggo.AssertNoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		testinggo.AssertNoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+	return dir
+}
+
+func makeNode(t *testing.T, alias string) bcgo.Node {
+	t.Helper()
+	a, err := account.GenerateRSA(alias)
+	testinggo.AssertNoError(t, err)
+	return node.New(a, cache.NewMemory(100), nil)
+}
+
+func TestAddDirectories(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Skip", func(t *testing.T) {
+		dir := makeDirectory(t, map[string]string{
+			"a.txt":     "alpha",
+			"empty.txt": "",
+			"sub/b.txt": "bravo",
+			"sub/c.txt": "alpha",
+		})
+		n := makeNode(t, "Tester")
+		client := spaceclientgo.NewSpaceClient()
+
+		// Duplicate content is only uploaded once
+		added, skipped, failed, err := addDirectories(ctx, client, n, nil, []string{dir}, false, true)
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, 3, added)
+		assert.Equal(t, 1, skipped)
+		assert.Equal(t, 0, failed)
+
+		// Files already uploaded are skipped
+		testinggo.AssertNoError(t, ioutil.WriteFile(filepath.Join(dir, "d.txt"), []byte("delta"), 0600))
+		added, skipped, failed, err = addDirectories(ctx, client, n, nil, []string{dir}, false, true)
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, 1, added)
+		assert.Equal(t, 4, skipped)
+		assert.Equal(t, 0, failed)
+
+		var names []string
+		testinggo.AssertNoError(t, client.AllMetas(n, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			names = append(names, meta.Name)
+			return nil
+		}))
+		assert.ElementsMatch(t, []string{"a.txt", "empty.txt", "b.txt", "d.txt"}, names)
+	})
+	t.Run("NoSkip", func(t *testing.T) {
+		dir := makeDirectory(t, map[string]string{
+			"a.txt":     "alpha",
+			"sub/c.txt": "alpha",
+		})
+		n := makeNode(t, "Tester")
+		client := spaceclientgo.NewSpaceClient()
+		for i := 0; i < 2; i++ {
+			added, skipped, failed, err := addDirectories(ctx, client, n, nil, []string{dir}, false, false)
+			testinggo.AssertNoError(t, err)
+			assert.Equal(t, 2, added)
+			assert.Equal(t, 0, skipped)
+			assert.Equal(t, 0, failed)
+		}
+	})
+	t.Run("Tag", func(t *testing.T) {
+		dir := makeDirectory(t, map[string]string{
+			"a.txt":         "alpha",
+			"sub/dir/b.txt": "bravo",
+		})
+		n := makeNode(t, "Tester")
+		client := spaceclientgo.NewSpaceClient()
+		added, _, _, err := addDirectories(ctx, client, n, nil, []string{dir}, true, true)
+		testinggo.AssertNoError(t, err)
+		assert.Equal(t, 2, added)
+
+		// Files are tagged with the directories leading to them, but not the hash of their content
+		tags := make(map[string][]string)
+		testinggo.AssertNoError(t, client.AllMetas(n, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+			tags[meta.Name] = nil
+			return client.AllTagsForHash(n, entry.RecordHash, func(e *bcgo.BlockEntry, tag *spacego.Tag) error {
+				tags[meta.Name] = append(tags[meta.Name], tag.Value)
+				return nil
+			})
+		}))
+		assert.Equal(t, 0, len(tags["a.txt"]))
+		assert.ElementsMatch(t, []string{"sub", "dir"}, tags["b.txt"])
+	})
+	t.Run("Cancelled", func(t *testing.T) {
+		dir := makeDirectory(t, map[string]string{
+			"a.txt": "alpha",
+		})
+		n := makeNode(t, "Tester")
+		client := spaceclientgo.NewSpaceClient()
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		added, _, _, err := addDirectories(ctx, client, n, nil, []string{dir}, false, true)
+		testinggo.AssertError(t, context.Canceled.Error(), err)
+		assert.Equal(t, 0, added)
+	})
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	COMPRESSION_NONE = ""
+	COMPRESSION_GZIP = "gzip"
+	COMPRESSION_ZSTD = "zstd"
+
+	// COMPRESSION_PARAMETER is appended to the MIME type in a file's meta data to record the compression applied to its content.
+	COMPRESSION_PARAMETER = "; compression="
+)
+
+// Codec compresses and decompresses the content of files.
+type Codec interface {
+	NewWriter(io.Writer) (io.WriteCloser, error)
+	NewReader(io.Reader) (io.ReadCloser, error)
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(writer), nil
+}
+
+func (gzipCodec) NewReader(reader io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(reader)
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(writer, zstd.WithEncoderConcurrency(1))
+}
+
+func (zstdCodec) NewReader(reader io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+// WithCompression sets the compression applied to the content of files added by the client, gzip and zstd are always available and other codecs must be given with WithCodec.
+// The compression is recorded in the file's meta data, and the bytes inserted by each delta of the file are compressed separately,
+// so deltas are computed, applied, and reported by History and WatchFileChanges as if the file were not compressed, and small changes write small deltas.
+func WithCompression(compression string) Option {
+	return func(c *spaceClient) {
+		c.compression = compression
+	}
+}
+
+// WithCodec sets the codec used for the compression with the given name, both to add files with WithCompression and to read files compressed by other clients.
+func WithCodec(compression string, codec Codec) Option {
+	return func(c *spaceClient) {
+		c.codecs[compression] = codec
+	}
+}
+
+// codec returns the codec of the given compression.
+func (c *spaceClient) codec(compression string) (Codec, error) {
+	codec, ok := c.codecs[compression]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported compression: %s", compression)
+	}
+	return codec, nil
+}
+
+// encodeType returns the given MIME type with the given compression recorded as a parameter.
+func encodeType(mime, compression string) string {
+	if compression == COMPRESSION_NONE {
+		return mime
+	}
+	return mime + COMPRESSION_PARAMETER + compression
+}
+
+// decodeType returns the given MIME type without the compression parameter, and the compression it recorded.
+func decodeType(mime string) (string, string) {
+	if i := strings.LastIndex(mime, COMPRESSION_PARAMETER); i >= 0 {
+		return mime[:i], mime[i+len(COMPRESSION_PARAMETER):]
+	}
+	return mime, COMPRESSION_NONE
+}
+
+// maxInsert returns the most bytes inserted by each delta written with the given compression, leaving room for the codec's overhead on data which does not compress.
+func maxInsert(compression string) uint64 {
+	if compression == COMPRESSION_NONE {
+		return spacego.MAX_SIZE_BYTES
+	}
+	return spacego.MAX_SIZE_BYTES - spacego.MAX_SIZE_BYTES/64
+}
+
+// compressDelta returns the given delta with the bytes it inserts compressed with the given compression.
+// Offsets and lengths are of the uncompressed content, so deltas are computed and applied as if the file were not compressed.
+func (c *spaceClient) compressDelta(compression string, delta *spacego.Delta) (*spacego.Delta, error) {
+	if compression == COMPRESSION_NONE || len(delta.Insert) == 0 {
+		return delta, nil
+	}
+	codec, err := c.codec(compression)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	writer, err := codec.NewWriter(&buffer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(delta.Insert); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return &spacego.Delta{
+		Offset: delta.Offset,
+		Delete: delta.Delete,
+		Insert: buffer.Bytes(),
+	}, nil
+}
+
+// decompressDelta decompresses the bytes inserted by the given delta, as written by compressDelta.
+func (c *spaceClient) decompressDelta(compression string, delta *spacego.Delta) error {
+	if compression == COMPRESSION_NONE || len(delta.Insert) == 0 {
+		return nil
+	}
+	codec, err := c.codec(compression)
+	if err != nil {
+		return err
+	}
+	reader, err := codec.NewReader(bytes.NewReader(delta.Insert))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	insert, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	delta.Insert = insert
+	return nil
+}
+
+// compressionForHash returns the compression applied to the content of the file with the given meta ID.
+func (c *spaceClient) compressionForHash(node bcgo.Node, metaId []byte) (string, error) {
+	s, err := c.stateForHash(node, metaId)
+	if err != nil {
+		return "", err
+	}
+	return s.compression, nil
+}
@@ -0,0 +1,289 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"container/list"
+	"encoding/base64"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DEFAULT_CONTENT_CACHE_LIMIT is the size in bytes of the memory content cache used when none is given.
+const DEFAULT_CONTENT_CACHE_LIMIT = 16 * 1024 * 1024
+
+// CachedContent is the content of a file as it was when its delta channel had the given head.
+type CachedContent struct {
+	Channel string
+	Head    []byte
+	Data    []byte
+}
+
+// ContentCache holds the content of files so they can be read without replaying their deltas.
+type ContentCache interface {
+	// Content returns the content cached under the given key, if any.
+	Content(string) (*CachedContent, bool)
+	// PutContent caches the given content under the given key, evicting the least recently used content if the cache exceeds its limit.
+	PutContent(string, *CachedContent) error
+	// Limit returns the maximum size in bytes of content held by the cache.
+	Limit() uint64
+}
+
+// WithContentCache sets the cache holding the content of files read by the client.
+func WithContentCache(cache ContentCache) Option {
+	return func(c *spaceClient) {
+		c.contentCache = cache
+	}
+}
+
+// WithFileSystemContentCache caches the content of files read by the client in the content directory under the client's root, holding at most limit bytes.
+// Content is stored decrypted, the memory content cache is used instead if the directory cannot be created.
+func WithFileSystemContentCache(limit uint64) Option {
+	return func(c *spaceClient) {
+		c.contentDirectoryLimit = limit
+	}
+}
+
+// openContentDirectory sets the content cache to the content directory under the client's root, if requested.
+func (c *spaceClient) openContentDirectory() {
+	if c.contentDirectoryLimit == 0 {
+		return
+	}
+	root, err := c.Root()
+	if err != nil {
+		c.logger.Warn("Could not open content cache", "error", err)
+		return
+	}
+	cache, err := NewFileSystemContentCache(filepath.Join(root, "content"), c.contentDirectoryLimit)
+	if err != nil {
+		c.logger.Warn("Could not open content cache", "error", err)
+		return
+	}
+	c.contentCache = cache
+}
+
+// contentKey returns the key of the content of the file with the given meta ID as read by the node's account.
+func contentKey(node bcgo.Node, metaId []byte) string {
+	return node.Account().Alias() + "/" + base64.RawURLEncoding.EncodeToString(metaId)
+}
+
+// cachedContent returns the cached content of the file with the given meta ID, if its delta channel has not changed since it was cached.
+func (c *spaceClient) cachedContent(node bcgo.Node, metaId []byte) ([]byte, bool) {
+	content, ok := c.contentCache.Content(contentKey(node, metaId))
+	if !ok {
+		return nil, false
+	}
+	if err := c.checkFile(node, metaId); err != nil {
+		// File is in the trash, or no longer readable
+		return nil, false
+	}
+	id := strings.TrimPrefix(content.Channel, spacego.DeltaChannelName(""))
+	deltas := node.OpenChannel(content.Channel, func() bcgo.Channel {
+		return spacego.OpenDeltaChannel(id)
+	})
+	c.refresh(node, deltas)
+	if head := deltas.Head(); head == nil || !bytes.Equal(head, content.Head) {
+		return nil, false
+	}
+	return content.Data, true
+}
+
+// putContent caches the given content of the file with the given meta ID, read from the given delta channel.
+func (c *spaceClient) putContent(node bcgo.Node, metaId []byte, deltas bcgo.Channel, data []byte) error {
+	head := deltas.Head()
+	if head == nil || uint64(len(data)) > c.contentCache.Limit() {
+		return nil
+	}
+	return c.contentCache.PutContent(contentKey(node, metaId), &CachedContent{
+		Channel: deltas.Name(),
+		Head:    head,
+		Data:    data,
+	})
+}
+
+type memoryContentCache struct {
+	sync.Mutex
+	limit   uint64
+	size    uint64
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type memoryContentEntry struct {
+	key     string
+	content *CachedContent
+}
+
+// NewMemoryContentCache returns a ContentCache which holds at most limit bytes of content in memory.
+func NewMemoryContentCache(limit uint64) ContentCache {
+	return &memoryContentCache{
+		limit:   limit,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *memoryContentCache) Content(key string) (*CachedContent, bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*memoryContentEntry).content, true
+}
+
+func (c *memoryContentCache) PutContent(key string, content *CachedContent) error {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	size := uint64(len(content.Data))
+	if size > c.limit {
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&memoryContentEntry{
+		key:     key,
+		content: content,
+	})
+	c.size += size
+	for c.size > c.limit {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *memoryContentCache) Limit() uint64 {
+	return c.limit
+}
+
+func (c *memoryContentCache) remove(e *list.Element) {
+	entry := c.order.Remove(e).(*memoryContentEntry)
+	delete(c.entries, entry.key)
+	c.size -= uint64(len(entry.content.Data))
+}
+
+type fileSystemContentCache struct {
+	sync.Mutex
+	directory string
+	limit     uint64
+}
+
+// NewFileSystemContentCache returns a ContentCache which holds at most limit bytes of content in files in the given directory.
+// The limit applies to the encoded size of the files, content is stored decrypted so the directory is only accessible to its owner.
+func NewFileSystemContentCache(directory string, limit uint64) (ContentCache, error) {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, err
+	}
+	return &fileSystemContentCache{
+		directory: directory,
+		limit:     limit,
+	}, nil
+}
+
+func (c *fileSystemContentCache) Content(key string) (*CachedContent, bool) {
+	c.Lock()
+	defer c.Unlock()
+	path := c.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	content := &CachedContent{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(content); err != nil {
+		return nil, false
+	}
+	// Update modification time so least recently used content is evicted first
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return content, true
+}
+
+func (c *fileSystemContentCache) PutContent(key string, content *CachedContent) error {
+	c.Lock()
+	defer c.Unlock()
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(content); err != nil {
+		return err
+	}
+	if uint64(buffer.Len()) > c.limit {
+		return nil
+	}
+	// Write to a temporary file first so readers never see partial content
+	file, err := ioutil.TempFile(c.directory, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), c.path(key)); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return c.evict()
+}
+
+func (c *fileSystemContentCache) Limit() uint64 {
+	return c.limit
+}
+
+func (c *fileSystemContentCache) path(key string) string {
+	return filepath.Join(c.directory, base64.RawURLEncoding.EncodeToString([]byte(key)))
+}
+
+// evict removes the least recently used content until the cache is within its limit.
+func (c *fileSystemContentCache) evict() error {
+	files, err := ioutil.ReadDir(c.directory)
+	if err != nil {
+		return err
+	}
+	var size uint64
+	for _, f := range files {
+		size += uint64(f.Size())
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, f := range files {
+		if size <= c.limit {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.directory, f.Name())); err != nil {
+			return err
+		}
+		size -= uint64(f.Size())
+	}
+	return nil
+}
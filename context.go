@@ -0,0 +1,423 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/financego"
+	"aletheiaware.com/spacego"
+	"context"
+	"io"
+	"reflect"
+)
+
+// contextNode is a node whose network calls end with its context.
+type contextNode struct {
+	bcgo.Node
+	ctx context.Context
+}
+
+func (n *contextNode) Network() bcgo.Network {
+	network := n.Node.Network()
+	if network == nil || reflect.ValueOf(network).IsNil() {
+		return nil
+	}
+	return &contextNetwork{
+		Network: network,
+		ctx:     n.ctx,
+	}
+}
+
+// contextNetwork is a network whose calls return the context's error if it ends before they complete.
+type contextNetwork struct {
+	bcgo.Network
+	ctx context.Context
+}
+
+func (n *contextNetwork) Head(channel string) (*bcgo.Reference, error) {
+	var reference *bcgo.Reference
+	err := n.call(func() (err error) {
+		reference, err = n.Network.Head(channel)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reference, nil
+}
+
+func (n *contextNetwork) Block(reference *bcgo.Reference) (*bcgo.Block, error) {
+	var block *bcgo.Block
+	err := n.call(func() (err error) {
+		block, err = n.Network.Block(reference)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (n *contextNetwork) Broadcast(channel bcgo.Channel, cache bcgo.Cache, hash []byte, block *bcgo.Block) error {
+	return n.call(func() error {
+		return n.Network.Broadcast(channel, cache, hash, block)
+	})
+}
+
+// call runs the given function, returning early if the context ends first.
+// The network has no way to cancel a call in flight, so it is abandoned rather than cancelled: the function keeps running in the background until the network returns, and its result is discarded.
+func (n *contextNetwork) call(f func() error) error {
+	if err := n.ctx.Err(); err != nil {
+		return err
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- f()
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-n.ctx.Done():
+		return n.ctx.Err()
+	}
+}
+
+// nodeContext returns the context of the given node, or the background context if it has none.
+func nodeContext(node bcgo.Node) context.Context {
+	switch n := node.(type) {
+	case *contextNode:
+		return n.ctx
+	case *serialNode:
+		return nodeContext(n.Node)
+	}
+	return context.Background()
+}
+
+// withContext calls the given function with a node bound to the given context.
+// Network calls return as soon as the context is done, but bcgo.Mine takes no context so it is only checked before and after mining, and functions which write may overrun it by as long as mining takes.
+// Their result is returned as is, including references to changes mined before a failure to push them.
+func withContext(ctx context.Context, node bcgo.Node, f func(bcgo.Node) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f(&contextNode{
+		Node: node,
+		ctx:  ctx,
+	})
+}
+
+// readContext calls the given function with a node bound to the given context.
+// Functions which read fall back to the cache when the network is unavailable, so the context's error is returned if it ended before the function completed as the result may be stale.
+func readContext(ctx context.Context, node bcgo.Node, f func(bcgo.Node) error) error {
+	if err := withContext(ctx, node, f); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// AddContext is like Add but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) AddContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, name, mime string, reader io.Reader) (*bcgo.Reference, error) {
+	var reference *bcgo.Reference
+	err := withContext(ctx, node, func(node bcgo.Node) (err error) {
+		reference, err = c.Add(node, listener, name, mime, reader)
+		return
+	})
+	return reference, err
+}
+
+// AddAllContext is like AddAll but ends when the given context is done, or if mining, once the block is mined.
+// Files whose content was not written before the context ended are left empty.
+func (c *spaceClient) AddAllContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, files []FileSpec) ([]*bcgo.Reference, error) {
+	var references []*bcgo.Reference
+	err := withContext(ctx, node, func(node bcgo.Node) (err error) {
+		references, err = c.AddAll(node, listener, files)
+		return
+	})
+	return references, err
+}
+
+// AmendContext is like Amend but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) AmendContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, channel bcgo.Channel, deltas ...*spacego.Delta) error {
+	return withContext(ctx, node, func(node bcgo.Node) error {
+		return c.Amend(node, listener, channel, deltas...)
+	})
+}
+
+// UpdateMetaContext is like UpdateMeta but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) UpdateMetaContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, name, mime string) error {
+	return withContext(ctx, node, func(node bcgo.Node) error {
+		return c.UpdateMeta(node, listener, metaId, name, mime)
+	})
+}
+
+// MetaForHashContext is like MetaForHash but ends when the given context is done.
+func (c *spaceClient) MetaForHashContext(ctx context.Context, node bcgo.Node, metaId []byte, callback spacego.MetaCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.MetaForHash(node, metaId, callback)
+	})
+}
+
+// AllMetasContext is like AllMetas but ends when the given context is done.
+func (c *spaceClient) AllMetasContext(ctx context.Context, node bcgo.Node, callback spacego.MetaCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.AllMetas(node, callback)
+	})
+}
+
+// ReadFileContext is like ReadFile but ends when the given context is done.
+func (c *spaceClient) ReadFileContext(ctx context.Context, node bcgo.Node, metaId []byte) (io.Reader, error) {
+	var reader io.Reader
+	err := readContext(ctx, node, func(node bcgo.Node) (err error) {
+		reader, err = c.ReadFile(node, metaId)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// OpenFileContext is like OpenFile but ends when the given context is done, including content read lazily after it returns.
+func (c *spaceClient) OpenFileContext(ctx context.Context, node bcgo.Node, metaId []byte) (io.ReadSeekCloser, error) {
+	var file io.ReadSeekCloser
+	err := readContext(ctx, node, func(node bcgo.Node) (err error) {
+		file, err = c.OpenFile(node, metaId)
+		return
+	})
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// ReadFileRangeContext is like ReadFileRange but ends when the given context is done.
+func (c *spaceClient) ReadFileRangeContext(ctx context.Context, node bcgo.Node, metaId []byte, offset, length int64) (io.Reader, error) {
+	var reader io.Reader
+	err := readContext(ctx, node, func(node bcgo.Node) (err error) {
+		reader, err = c.ReadFileRange(node, metaId, offset, length)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// ReadFileAtContext is like ReadFileAt but ends when the given context is done.
+func (c *spaceClient) ReadFileAtContext(ctx context.Context, node bcgo.Node, metaId []byte, timestamp uint64) (io.Reader, error) {
+	var reader io.Reader
+	err := readContext(ctx, node, func(node bcgo.Node) (err error) {
+		reader, err = c.ReadFileAt(node, metaId, timestamp)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// ReadFileVersionContext is like ReadFileVersion but ends when the given context is done.
+func (c *spaceClient) ReadFileVersionContext(ctx context.Context, node bcgo.Node, metaId, recordHash []byte) (io.Reader, error) {
+	var reader io.Reader
+	err := readContext(ctx, node, func(node bcgo.Node) (err error) {
+		reader, err = c.ReadFileVersion(node, metaId, recordHash)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// HistoryContext is like History but ends when the given context is done.
+func (c *spaceClient) HistoryContext(ctx context.Context, node bcgo.Node, metaId []byte, callback HistoryCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.History(node, metaId, callback)
+	})
+}
+
+// DiffContext is like Diff but ends when the given context is done.
+func (c *spaceClient) DiffContext(ctx context.Context, node bcgo.Node, metaId, fromRecord, toRecord []byte) (io.Reader, error) {
+	var reader io.Reader
+	err := readContext(ctx, node, func(node bcgo.Node) (err error) {
+		reader, err = c.Diff(node, metaId, fromRecord, toRecord)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// WriteFileContext is like WriteFile but ends when the given context is done, including the amendment made when the writer is closed, or if mining, once the block is mined.
+func (c *spaceClient) WriteFileContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte) (io.WriteCloser, error) {
+	var writer io.WriteCloser
+	err := withContext(ctx, node, func(node bcgo.Node) (err error) {
+		writer, err = c.WriteFile(node, listener, metaId)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// RevertContext is like Revert but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) RevertContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId, recordHash []byte) error {
+	return withContext(ctx, node, func(node bcgo.Node) error {
+		return c.Revert(node, listener, metaId, recordHash)
+	})
+}
+
+// AddPreviewContext is like AddPreview but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) AddPreviewContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, preview []*spacego.Preview) ([]*bcgo.Reference, error) {
+	var references []*bcgo.Reference
+	err := withContext(ctx, node, func(node bcgo.Node) (err error) {
+		references, err = c.AddPreview(node, listener, metaId, preview)
+		return
+	})
+	return references, err
+}
+
+// AllPreviewsForHashContext is like AllPreviewsForHash but ends when the given context is done.
+func (c *spaceClient) AllPreviewsForHashContext(ctx context.Context, node bcgo.Node, metaId []byte, callback spacego.PreviewCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.AllPreviewsForHash(node, metaId, callback)
+	})
+}
+
+// AddTagContext is like AddTag but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) AddTagContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, tag []string) ([]*bcgo.Reference, error) {
+	var references []*bcgo.Reference
+	err := withContext(ctx, node, func(node bcgo.Node) (err error) {
+		references, err = c.AddTag(node, listener, metaId, tag)
+		return
+	})
+	return references, err
+}
+
+// AllTagsForHashContext is like AllTagsForHash but ends when the given context is done.
+func (c *spaceClient) AllTagsForHashContext(ctx context.Context, node bcgo.Node, metaId []byte, callback spacego.TagCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.AllTagsForHash(node, metaId, callback)
+	})
+}
+
+// RemoveTagContext is like RemoveTag but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) RemoveTagContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, tag []string) ([]*bcgo.Reference, error) {
+	var references []*bcgo.Reference
+	err := withContext(ctx, node, func(node bcgo.Node) (err error) {
+		references, err = c.RemoveTag(node, listener, metaId, tag)
+		return
+	})
+	return references, err
+}
+
+// ShareContext is like Share but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) ShareContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, aliases ...string) error {
+	return withContext(ctx, node, func(node bcgo.Node) error {
+		return c.Share(node, listener, metaId, aliases...)
+	})
+}
+
+// SharedMetasContext is like SharedMetas but ends when the given context is done.
+func (c *spaceClient) SharedMetasContext(ctx context.Context, node bcgo.Node, callback SharedMetaCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.SharedMetas(node, callback)
+	})
+}
+
+// RevokeContext is like Revoke but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) RevokeContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, aliases ...string) error {
+	return withContext(ctx, node, func(node bcgo.Node) error {
+		return c.Revoke(node, listener, metaId, aliases...)
+	})
+}
+
+// DeleteContext is like Delete but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) DeleteContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte) error {
+	return withContext(ctx, node, func(node bcgo.Node) error {
+		return c.Delete(node, listener, metaId)
+	})
+}
+
+// RestoreContext is like Restore but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) RestoreContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte) error {
+	return withContext(ctx, node, func(node bcgo.Node) error {
+		return c.Restore(node, listener, metaId)
+	})
+}
+
+// TrashContext is like Trash but ends when the given context is done.
+func (c *spaceClient) TrashContext(ctx context.Context, node bcgo.Node, callback spacego.MetaCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.Trash(node, callback)
+	})
+}
+
+// EmptyTrashContext is like EmptyTrash but ends when the given context is done, or if mining, once the block is mined.
+func (c *spaceClient) EmptyTrashContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener) error {
+	return withContext(ctx, node, func(node bcgo.Node) error {
+		return c.EmptyTrash(node, listener)
+	})
+}
+
+// SearchMetaContext is like SearchMeta but ends when the given context is done.
+func (c *spaceClient) SearchMetaContext(ctx context.Context, node bcgo.Node, filter spacego.MetaFilter, callback spacego.MetaCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.SearchMeta(node, filter, callback)
+	})
+}
+
+// SearchTagContext is like SearchTag but ends when the given context is done.
+func (c *spaceClient) SearchTagContext(ctx context.Context, node bcgo.Node, filter spacego.TagFilter, callback spacego.MetaCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.SearchTag(node, filter, callback)
+	})
+}
+
+// ContentHashesContext is like ContentHashes but ends when the given context is done.
+func (c *spaceClient) ContentHashesContext(ctx context.Context, node bcgo.Node, callback ContentHashCallback) error {
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.ContentHashes(node, callback)
+	})
+}
+
+// RegistrationContext is like Registration but ends when the given context is done.
+func (c *spaceClient) RegistrationContext(ctx context.Context, merchant string, callback financego.RegistrationCallback) error {
+	node, err := c.Node()
+	if err != nil {
+		return err
+	}
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.merchantRegistration(node, merchant, callback)
+	})
+}
+
+// SubscriptionContext is like Subscription but ends when the given context is done.
+func (c *spaceClient) SubscriptionContext(ctx context.Context, merchant string, callback financego.SubscriptionCallback) error {
+	node, err := c.Node()
+	if err != nil {
+		return err
+	}
+	return readContext(ctx, node, func(node bcgo.Node) error {
+		return c.merchantSubscription(node, merchant, callback)
+	})
+}
@@ -0,0 +1,279 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// DIFF_CONTEXT is the number of unchanged lines shown around each change in a unified diff.
+const DIFF_CONTEXT = 3
+
+// Diff the file with the given meta ID between the versions after the deltas with the given record hashes.
+// A nil fromRecord diffs from the empty file, and a nil toRecord diffs to the current content.
+// Text files are compared as a unified diff, all other files with a summary of the bytes changed.
+func (c *spaceClient) Diff(node bcgo.Node, metaId, fromRecord, toRecord []byte) (io.Reader, error) {
+	from, err := c.readDiffVersion(node, metaId, fromRecord, false)
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.readDiffVersion(node, metaId, toRecord, true)
+	if err != nil {
+		return nil, err
+	}
+	var text bool
+	if err := c.MetaForHash(node, metaId, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		text = strings.HasPrefix(meta.Type, "text/")
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	mId := base64.RawURLEncoding.EncodeToString(metaId)
+	fromName := mId + "@" + versionName(fromRecord, "empty")
+	toName := mId + "@" + versionName(toRecord, "current")
+	buffer := &bytes.Buffer{}
+	if text {
+		writeUnifiedDiff(buffer, fromName, toName, splitLines(string(from)), splitLines(string(to)))
+	} else {
+		writeBinaryDiff(buffer, fromName, toName, from, to)
+	}
+	return buffer, nil
+}
+
+// readDiffVersion returns the content of the file after the delta with the given record hash, or the current content if it is nil and current is set.
+func (c *spaceClient) readDiffVersion(node bcgo.Node, metaId, recordHash []byte, current bool) ([]byte, error) {
+	var (
+		reader io.Reader
+		err    error
+	)
+	switch {
+	case recordHash != nil:
+		reader, err = c.ReadFileVersion(node, metaId, recordHash)
+	case current:
+		reader, err = c.ReadFile(node, metaId)
+	default:
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(reader)
+}
+
+func versionName(recordHash []byte, fallback string) string {
+	if recordHash == nil {
+		return fallback
+	}
+	return base64.RawURLEncoding.EncodeToString(recordHash)
+}
+
+// splitLines splits the given text into lines, each retaining its line ending.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edit is an operation in a line diff, one of ' ' (keep), '-' (delete), or '+' (insert).
+type edit struct {
+	op   byte
+	line string
+}
+
+// diffLines returns the shortest edit script transforming a into b, using the linear space variant of Myers' algorithm.
+func diffLines(a, b []string) []edit {
+	return diffRange(nil, a, b)
+}
+
+// diffRange appends the edits transforming a into b to the given edits.
+// The problem is split at the middle snake of the shortest edit script so memory use is linear in the size of the input.
+func diffRange(edits []edit, a, b []string) []edit {
+	// Trim common prefix and suffix
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		edits = append(edits, edit{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	common := 0
+	for common < len(a) && common < len(b) && a[len(a)-1-common] == b[len(b)-1-common] {
+		common++
+	}
+	suffix := a[len(a)-common:]
+	a, b = a[:len(a)-common], b[:len(b)-common]
+	switch {
+	case len(a) == 0:
+		for _, l := range b {
+			edits = append(edits, edit{'+', l})
+		}
+	case len(b) == 0:
+		for _, l := range a {
+			edits = append(edits, edit{'-', l})
+		}
+	default:
+		x, y, u, v := middleSnake(a, b)
+		edits = diffRange(edits, a[:x], b[:y])
+		for _, l := range a[x:u] {
+			edits = append(edits, edit{' ', l})
+		}
+		edits = diffRange(edits, a[u:], b[v:])
+	}
+	for _, l := range suffix {
+		edits = append(edits, edit{' ', l})
+	}
+	return edits
+}
+
+// middleSnake returns the start and end of the snake in the middle of the shortest edit script transforming a into b, found by searching forwards from the start and backwards from the end until the paths overlap.
+func middleSnake(a, b []string) (int, int, int, int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	offset := max + 1
+	// forward[k] is the furthest x reached on diagonal k from the start, backward[k] is the furthest distance reached on diagonal k from the end
+	forward := make([]int, 2*max+3)
+	backward := make([]int, 2*max+3)
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if r := delta - k; odd && r >= -(d-1) && r <= d-1 && x+backward[offset+r] >= n {
+				return sx, sy, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if f := delta - k; !odd && f >= -d && f <= d && x+forward[offset+f] >= n {
+				return n - x, m - y, n - sx, m - sy
+			}
+		}
+	}
+	// Unreachable, the paths overlap once d reaches half the length of the edit script
+	return 0, 0, 0, 0
+}
+
+// writeUnifiedDiff writes the difference between the given lines in unified format.
+func writeUnifiedDiff(output io.Writer, fromName, toName string, a, b []string) {
+	edits := diffLines(a, b)
+	var changes []int
+	for i, e := range edits {
+		if e.op != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(output, "--- %s\n", fromName)
+	fmt.Fprintf(output, "+++ %s\n", toName)
+	for i := 0; i < len(changes); {
+		// Group changes whose context overlaps into a single hunk
+		start := changes[i] - DIFF_CONTEXT
+		if start < 0 {
+			start = 0
+		}
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*DIFF_CONTEXT {
+			j++
+		}
+		end := changes[j] + DIFF_CONTEXT + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+		i = j + 1
+
+		var aStart, bStart, aLength, bLength int
+		for _, e := range edits[:start] {
+			if e.op != '+' {
+				aStart++
+			}
+			if e.op != '-' {
+				bStart++
+			}
+		}
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				aLength++
+			}
+			if e.op != '-' {
+				bLength++
+			}
+		}
+		if aLength > 0 {
+			aStart++
+		}
+		if bLength > 0 {
+			bStart++
+		}
+		fmt.Fprintf(output, "@@ -%d,%d +%d,%d @@\n", aStart, aLength, bStart, bLength)
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(output, "%c%s", e.op, e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				fmt.Fprint(output, "\n\\ No newline at end of file\n")
+			}
+		}
+	}
+}
+
+// writeBinaryDiff writes a summary of the bytes changed between the given contents.
+func writeBinaryDiff(output io.Writer, fromName, toName string, a, b []byte) {
+	if bytes.Equal(a, b) {
+		return
+	}
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	fmt.Fprintf(output, "Binary files %s and %s differ\n", fromName, toName)
+	fmt.Fprintf(output, "Size: %d -> %d bytes\n", len(a), len(b))
+	fmt.Fprintf(output, "Changed: %d bytes at offset %d replaced with %d bytes\n", len(a)-prefix-suffix, prefix, len(b)-prefix-suffix)
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by SpaceClient are wrapped so they can be identified with errors.Is.
+var (
+	// ErrFileNotFound is returned when the account has no file with the given meta ID, and no such file has been shared with it.
+	ErrFileNotFound = errors.New("Could not find file")
+	// ErrVersionNotFound is returned when a file has no delta with the given record hash.
+	ErrVersionNotFound = errors.New("Could not find version")
+	// ErrAccessDenied is returned when changing a file which was shared with the account, but is owned by another alias.
+	ErrAccessDenied = errors.New("Access denied")
+	// ErrNotDecryptable is returned when the meta data of a file is held in the local cache, but could not be decrypted by the account.
+	ErrNotDecryptable = errors.New("Could not decrypt file")
+	// ErrNetworkUnavailable is returned when changes could not be pushed to peers, or a file could not be found after peers could not be reached.
+	// Changes are still mined into the local cache, and are pushed with the next change to the same channel.
+	// Functions returning references to the changes they make return them along with this error.
+	ErrNetworkUnavailable = errors.New("Network unavailable")
+)
+
+// errFileNotFound returns the error reported when the account's file with the given meta ID could not be read.
+// Only the local cache and the outcome of the last refresh of the account's meta channel are consulted, no requests are made to peers.
+func (c *spaceClient) errFileNotFound(node bcgo.Node, metaId []byte) error {
+	mId := base64.RawURLEncoding.EncodeToString(metaId)
+	account := node.Account()
+	alias := account.Alias()
+	name := spacego.MetaChannelName(alias)
+	metas := node.OpenChannel(name, func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	if head := metas.Head(); head != nil {
+		var found, readable bool
+		bcgo.Iterate(name, head, nil, node.Cache(), nil, func(hash []byte, block *bcgo.Block) error {
+			for _, e := range block.Entry {
+				if bytes.Equal(e.RecordHash, metaId) {
+					found = true
+					return errStopped
+				}
+			}
+			return nil
+		})
+		if found {
+			bcgo.Read(name, head, nil, node.Cache(), nil, account, metaId, func(*bcgo.BlockEntry, []byte, []byte) error {
+				readable = true
+				return errStopped
+			})
+			if !readable {
+				return fmt.Errorf("%w: %s", ErrNotDecryptable, mId)
+			}
+		}
+	}
+	if err, ok := c.unreachable.Load(name); ok {
+		// File may exist on peers which could not be reached
+		return fmt.Errorf("%w: %s: %v", ErrNetworkUnavailable, mId, err)
+	}
+	return fmt.Errorf("%w: %s", ErrFileNotFound, mId)
+}
+
+// errNotOwned returns the error reported when changing a file the account does not own, which is ErrAccessDenied if the file was shared with the account by another alias.
+func (c *spaceClient) errNotOwned(node bcgo.Node, metaId []byte) error {
+	alias := node.Account().Alias()
+	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	if s, err := c.sharedFile(node, metas, metaId); err == nil && s != nil {
+		return fmt.Errorf("%w: %s is owned by %s", ErrAccessDenied, base64.RawURLEncoding.EncodeToString(metaId), s.owner)
+	}
+	return c.errFileNotFound(node, metaId)
+}
+
+// pushError returns the error reported when the given channel could not be pushed to peers.
+// Peers do not report why they rejected a block, so rejections are reported the same as failures to reach them.
+func pushError(channel bcgo.Channel, err error) error {
+	return fmt.Errorf("%w: %s: %v", ErrNetworkUnavailable, channel.Name(), err)
+}
+
+// pushed returns nil if the given mining error only reports that changes could not be pushed to peers, recording it in the given failure so the operation can complete before reporting it.
+func pushed(err error, failure *error) error {
+	if errors.Is(err, ErrNetworkUnavailable) {
+		if *failure == nil {
+			*failure = err
+		}
+		return nil
+	}
+	return err
+}
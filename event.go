@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger receives levelled messages with alternating key-value attributes, a *slog.Logger satisfies this interface.
+// Messages may be logged from several goroutines at once, such as the workers of SearchTag and AddAll, so loggers must be safe for concurrent use.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// WithLogger sets the logger used by the client, messages above debug level are written to the standard logger if none is given.
+func WithLogger(logger Logger) Option {
+	return func(c *spaceClient) {
+		c.logger = logger
+	}
+}
+
+// defaultLogger writes messages above debug level to the standard logger.
+type defaultLogger struct{}
+
+func (defaultLogger) Debug(msg string, args ...interface{}) {}
+
+func (l defaultLogger) Info(msg string, args ...interface{}) {
+	l.print("INFO", msg, args)
+}
+
+func (l defaultLogger) Warn(msg string, args ...interface{}) {
+	l.print("WARN", msg, args)
+}
+
+func (l defaultLogger) Error(msg string, args ...interface{}) {
+	l.print("ERROR", msg, args)
+}
+
+func (defaultLogger) print(level, msg string, args []interface{}) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	log.Println(b.String())
+}
+
+// EventType identifies what happened to a channel.
+type EventType int
+
+const (
+	// EVENT_REFRESH_FAILED is reported when a channel could not be refreshed from peers, the cached channel is used instead.
+	EVENT_REFRESH_FAILED EventType = iota + 1
+	// EVENT_MINED is reported when a block is mined into a channel.
+	EVENT_MINED
+	// EVENT_PUSHED is reported when a channel is pushed to peers.
+	EVENT_PUSHED
+	// EVENT_PUSH_FAILED is reported when a channel could not be pushed to peers.
+	EVENT_PUSH_FAILED
+	// EVENT_DELTA_WRITTEN is reported when a delta is written to a file's delta channel, ready to be mined.
+	EVENT_DELTA_WRITTEN
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EVENT_REFRESH_FAILED:
+		return "RefreshFailed"
+	case EVENT_MINED:
+		return "Mined"
+	case EVENT_PUSHED:
+		return "Pushed"
+	case EVENT_PUSH_FAILED:
+		return "PushFailed"
+	case EVENT_DELTA_WRITTEN:
+		return "DeltaWritten"
+	}
+	return "Unknown"
+}
+
+// Event describes something that happened to a channel while the client was working with it.
+type Event struct {
+	Type    EventType
+	Channel string
+	// Hash is the hash of the block mined or pushed, or of the record holding the delta written.
+	Hash []byte
+	// Error is the cause of a failed refresh or push.
+	Error error
+}
+
+// EventHook is triggered with each event, it is called synchronously so should return promptly.
+// Calls are serialized so the hook need not be safe for concurrent use, but it must not call the client as that may deadlock.
+type EventHook func(*Event)
+
+// WithEventHook sets the hook triggered with each event.
+func WithEventHook(hook EventHook) Option {
+	return func(c *spaceClient) {
+		c.hook = hook
+	}
+}
+
+// event triggers the event hook, if set.
+func (c *spaceClient) event(e *Event) {
+	if c.hook != nil {
+		c.events.Lock()
+		defer c.events.Unlock()
+		c.hook(e)
+	}
+}
+
+// mined reports the block with the given hash was mined into the given channel.
+func (c *spaceClient) mined(channel bcgo.Channel, hash []byte) {
+	c.logger.Debug("Mined block", "channel", channel.Name(), "hash", base64.RawURLEncoding.EncodeToString(hash))
+	c.event(&Event{
+		Type:    EVENT_MINED,
+		Channel: channel.Name(),
+		Hash:    hash,
+	})
+}
+
+// written reports the delta in the given record was written to the given channel.
+func (c *spaceClient) written(channel bcgo.Channel, reference *bcgo.Reference) {
+	c.logger.Debug("Wrote delta", "channel", channel.Name(), "record", base64.RawURLEncoding.EncodeToString(reference.RecordHash))
+	c.event(&Event{
+		Type:    EVENT_DELTA_WRITTEN,
+		Channel: channel.Name(),
+		Hash:    reference.RecordHash,
+	})
+}
@@ -1,6 +1,6 @@
 module aletheiaware.com/spaceclientgo
 
-go 1.15
+go 1.16
 
 require (
 	aletheiaware.com/aliasgo v1.2.3
@@ -10,5 +10,6 @@ require (
 	aletheiaware.com/spacego v1.2.4
 	aletheiaware.com/testinggo v1.2.2
 	github.com/golang/protobuf v1.5.2
+	github.com/klauspost/compress v1.15.9
 	github.com/stretchr/testify v1.7.0
 )
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package notify implements the line based protocol used by the TCP notifier to subscribe to channel updates.
+package notify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// PORT is the port a notifying peer is expected to listen on for subscriptions to channel updates.
+	PORT = 22322
+
+	// TIMEOUT is the time allowed to connect to a peer.
+	TIMEOUT = 10 * time.Second
+)
+
+// WriteSubscription writes a subscription to updates of the channel with the given name.
+func WriteSubscription(writer io.Writer, channel string) error {
+	_, err := fmt.Fprintf(writer, "SUBSCRIBE %s\n", channel)
+	return err
+}
+
+// ReadSubscription reads a subscription written by WriteSubscription, returning the name of the channel.
+func ReadSubscription(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 || fields[0] != "SUBSCRIBE" {
+		return "", fmt.Errorf("Invalid subscription: %s", strings.TrimSpace(line))
+	}
+	return fields[1], nil
+}
+
+// WriteNotification writes a notification that the channel with the given name has been updated to the given head.
+func WriteNotification(writer io.Writer, channel string, head []byte) error {
+	_, err := fmt.Fprintf(writer, "%s %s\n", channel, base64.RawURLEncoding.EncodeToString(head))
+	return err
+}
+
+// ReadNotification reads a notification written by WriteNotification, returning the name of the channel and its new head.
+func ReadNotification(reader *bufio.Reader) (string, []byte, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", nil, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return "", nil, fmt.Errorf("Invalid notification: %s", strings.TrimSpace(line))
+	}
+	head, err := base64.RawURLEncoding.DecodeString(fields[1])
+	if err != nil {
+		return "", nil, err
+	}
+	return fields[0], head, nil
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"encoding/base64"
+	"github.com/golang/protobuf/proto"
+	"sort"
+	"strings"
+)
+
+const (
+	// MIME_TYPE_DELETED marks a version of a file's metadata written when the file was moved to the trash.
+	MIME_TYPE_DELETED = "application/x-space-deleted"
+	// MIME_TYPE_PURGED marks a version of a file's metadata written when the file was removed from the trash.
+	MIME_TYPE_PURGED = "application/x-space-purged"
+)
+
+// file holds every readable version of a file's metadata.
+//
+// A file is identified by the hash of the first meta record written for it,
+// later meta records in the same channel which reference that record are
+// newer versions of the file.
+type file struct {
+	id        []byte
+	timestamp uint64
+	versions  []*version
+}
+
+type version struct {
+	entry       *bcgo.BlockEntry
+	meta        *spacego.Meta
+	compression string
+}
+
+// latest returns the most recent version of the file.
+func (f *file) latest() *version {
+	return f.versions[len(f.versions)-1]
+}
+
+// compression returns the compression applied to the content of the file.
+func (f *file) compression() string {
+	for _, v := range f.versions {
+		if v.compression != COMPRESSION_NONE {
+			return v.compression
+		}
+	}
+	return COMPRESSION_NONE
+}
+
+// marshal returns the given meta data encoded as a version of the file, recording the compression of its content unless the version marks the file in the trash.
+func (f *file) marshal(meta *spacego.Meta) ([]byte, error) {
+	switch meta.Type {
+	case MIME_TYPE_DELETED, MIME_TYPE_PURGED:
+		return proto.Marshal(meta)
+	}
+	m := proto.Clone(meta).(*spacego.Meta)
+	m.Type = encodeType(m.Type, f.compression())
+	return proto.Marshal(m)
+}
+
+// live returns the most recent version of the file which is not a deletion marker, or nil.
+func (f *file) live() *version {
+	for i := len(f.versions) - 1; i >= 0; i-- {
+		switch f.versions[i].meta.Type {
+		case MIME_TYPE_DELETED, MIME_TYPE_PURGED:
+		default:
+			return f.versions[i]
+		}
+	}
+	return nil
+}
+
+// deleted returns true if the file has been moved to the trash.
+func (f *file) deleted() bool {
+	t := f.latest().meta.Type
+	return t == MIME_TYPE_DELETED || t == MIME_TYPE_PURGED
+}
+
+// purged returns true if the file has been removed from the trash.
+func (f *file) purged() bool {
+	return f.latest().meta.Type == MIME_TYPE_PURGED
+}
+
+// entry returns an entry for the latest version of the file, identified by the file's meta ID.
+func (f *file) entry() *bcgo.BlockEntry {
+	e := f.latest().entry
+	if bytes.Equal(e.RecordHash, f.id) {
+		return e
+	}
+	return &bcgo.BlockEntry{
+		RecordHash: f.id,
+		Record:     e.Record,
+	}
+}
+
+// reference returns a reference to the file in the given meta channel.
+func (f *file) reference(channel string) *bcgo.Reference {
+	return &bcgo.Reference{
+		Timestamp:   f.timestamp,
+		ChannelName: channel,
+		RecordHash:  f.id,
+	}
+}
+
+// holds returns true if the deltas of the file are held in the channel with the given name,
+// either the file's own delta channel or a channel the file was moved to by Revoke.
+func (f *file) holds(channel string) bool {
+	if channel == spacego.DeltaChannelName(base64.RawURLEncoding.EncodeToString(f.id)) {
+		return true
+	}
+	for _, v := range f.versions {
+		for _, r := range v.entry.Record.Reference {
+			if r.ChannelName == channel {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// access returns the aliases granted access to the latest version of the file.
+func (f *file) access() []string {
+	return accessAliases(f.latest().entry.Record)
+}
+
+// readFiles reads all files in the given meta channel which are readable by the given account.
+// Only records created by the alias owning the channel are read, so other aliases cannot add files or versions to it.
+// Files are returned in the order they were last modified, most recent first.
+func readFiles(metas bcgo.Channel, cache bcgo.Cache, network bcgo.Network, account bcgo.Account) ([]*file, error) {
+	owner := strings.TrimPrefix(metas.Name(), spacego.MetaChannelName(""))
+	var files []*file
+	index := make(map[string]*file)
+	if err := spacego.ReadMeta(metas, cache, network, account, nil, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		if entry.Record.Creator != owner {
+			// Entry was written by another alias, such as a file shared with the owner
+			return nil
+		}
+		id, timestamp, ok := fileId(metas.Name(), entry)
+		if !ok {
+			// Entry refers to a file in another channel
+			return nil
+		}
+		f, ok := index[string(id)]
+		if !ok {
+			f = &file{
+				id:        id,
+				timestamp: timestamp,
+			}
+			index[string(id)] = f
+			files = append(files, f)
+		}
+		if bytes.Equal(id, entry.RecordHash) {
+			f.timestamp = entry.Record.Timestamp
+		}
+		var compression string
+		meta.Type, compression = decodeType(meta.Type)
+		f.versions = append(f.versions, &version{
+			entry:       entry,
+			meta:        meta,
+			compression: compression,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		sort.SliceStable(f.versions, func(i, j int) bool {
+			return f.versions[i].entry.Record.Timestamp < f.versions[j].entry.Record.Timestamp
+		})
+	}
+	return files, nil
+}
+
+// readFile reads the file with the given meta ID from the given meta channel, returning nil if the file could not be found.
+func readFile(metas bcgo.Channel, cache bcgo.Cache, network bcgo.Network, account bcgo.Account, metaId []byte) (*file, error) {
+	files, err := readFiles(metas, cache, network, account)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if bytes.Equal(f.id, metaId) {
+			return f, nil
+		}
+	}
+	return nil, nil
+}
+
+// latestAccess returns the aliases granted access to the latest version of each file in the given meta channel, keyed by meta ID.
+// Only the unencrypted headers of records created by the alias owning the channel are read, so versions the account cannot decrypt are included,
+// such as the version written by Revoke once the account's access has been revoked.
+func latestAccess(metas bcgo.Channel, cache bcgo.Cache, network bcgo.Network) (map[string][]string, error) {
+	owner := strings.TrimPrefix(metas.Name(), spacego.MetaChannelName(""))
+	latest := make(map[string]*bcgo.Record)
+	if head := metas.Head(); head != nil {
+		if err := bcgo.Iterate(metas.Name(), head, nil, cache, network, func(hash []byte, block *bcgo.Block) error {
+			for _, e := range block.Entry {
+				if e.Record.Creator != owner {
+					continue
+				}
+				id, _, ok := fileId(metas.Name(), e)
+				if !ok {
+					continue
+				}
+				if r, ok := latest[string(id)]; !ok || e.Record.Timestamp > r.Timestamp {
+					latest[string(id)] = e.Record
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	access := make(map[string][]string, len(latest))
+	for id, r := range latest {
+		access[id] = accessAliases(r)
+	}
+	return access, nil
+}
+
+// fileId returns the meta ID and timestamp of the file the given meta entry is a version of.
+// Returns false if the entry refers to a file in another meta channel.
+func fileId(channel string, entry *bcgo.BlockEntry) ([]byte, uint64, bool) {
+	for _, r := range entry.Record.Reference {
+		if r.ChannelName == channel {
+			return r.RecordHash, r.Timestamp, true
+		}
+	}
+	if len(entry.Record.Reference) > 0 {
+		return nil, 0, false
+	}
+	return entry.RecordHash, entry.Record.Timestamp, true
+}
+
+// accessAliases returns the aliases granted access to the given record.
+func accessAliases(record *bcgo.Record) []string {
+	var aliases []string
+	for _, a := range record.Access {
+		if !containsAlias(aliases, a.Alias) {
+			aliases = append(aliases, a.Alias)
+		}
+	}
+	return aliases
+}
+
+func containsAlias(aliases []string, alias string) bool {
+	for _, a := range aliases {
+		if a == alias {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/spaceclientgo/internal/notify"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// notifier delivers notifications of updates to channels.
+// S P A C E and BC peers do not serve subscriptions, so notifiers are kept internal, and only used by tests, until a peer does.
+type notifier interface {
+	// Subscribe triggers the callback with the new head of the channel with the given name whenever it is updated.
+	// The returned channel receives an error when the subscription ends, either because the context is done or the connection failed.
+	Subscribe(context.Context, string, func([]byte)) (<-chan error, error)
+}
+
+// withNotifier sets the notifier used to watch for remote updates, channels are polled if none is given, which is the default.
+func withNotifier(notifier notifier) Option {
+	return func(c *spaceClient) {
+		c.notifier = notifier
+	}
+}
+
+type tcpNotifier struct {
+	peers []string
+}
+
+// newTCPNotifier returns a notifier which subscribes to updates over a long-lived connection to the first available of the given peers.
+//
+// The protocol is line based: the subscriber writes "SUBSCRIBE <channel>", and the peer writes "<channel> <head>" whenever the channel is updated, where head is the base64 encoded hash of the new head block.
+// No S P A C E or BC server implements this protocol, the peers given must run a service that does.
+func newTCPNotifier(peers ...string) notifier {
+	return &tcpNotifier{
+		peers: peers,
+	}
+}
+
+func (n *tcpNotifier) Subscribe(ctx context.Context, channel string, callback func([]byte)) (<-chan error, error) {
+	conn, err := n.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := notify.WriteSubscription(conn, channel); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		// Close connection when context is done to unblock reader
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+	result := make(chan error, 1)
+	go func() {
+		defer close(done)
+		reader := bufio.NewReader(conn)
+		for {
+			name, head, err := notify.ReadNotification(reader)
+			if err != nil {
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				result <- err
+				return
+			}
+			if name == channel {
+				callback(head)
+			}
+		}
+	}()
+	return result, nil
+}
+
+func (n *tcpNotifier) connect(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout: notify.TIMEOUT,
+	}
+	var errs []string
+	for _, p := range n.peers {
+		address := p
+		if _, _, err := net.SplitHostPort(p); err != nil {
+			address = net.JoinHostPort(p, strconv.Itoa(notify.PORT))
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		return conn, nil
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("No peers to subscribe to")
+	}
+	return nil, fmt.Errorf("Could not subscribe to peers: %s", strings.Join(errs, ", "))
+}
@@ -0,0 +1,257 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+const (
+	// PREVIEW_IMAGE_SIZE is the maximum width and height in pixels of an image preview.
+	PREVIEW_IMAGE_SIZE = 256
+	// PREVIEW_IMAGE_SOURCE_LIMIT is the maximum size in bytes of an image for which a preview is generated.
+	PREVIEW_IMAGE_SOURCE_LIMIT = 32 * 1024 * 1024
+	// PREVIEW_IMAGE_PIXEL_LIMIT is the maximum number of pixels in an image for which a preview is generated, as decoding allocates memory for every pixel.
+	PREVIEW_IMAGE_PIXEL_LIMIT = 64 * 1024 * 1024
+	// PREVIEW_TEXT_LINES is the maximum number of lines in a text preview.
+	PREVIEW_TEXT_LINES = 10
+	// PREVIEW_TEXT_SOURCE_LIMIT is the maximum size in bytes of text read to generate a preview.
+	PREVIEW_TEXT_SOURCE_LIMIT = 4 * 1024
+)
+
+// AddPreview adds the given previews to the file with the given meta ID.
+func (c *spaceClient) AddPreview(node bcgo.Node, listener bcgo.MiningListener, metaId []byte, preview []*spacego.Preview) ([]*bcgo.Reference, error) {
+	account := node.Account()
+	alias := account.Alias()
+	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	c.refresh(node, metas)
+	mId := base64.RawURLEncoding.EncodeToString(metaId)
+	previews := node.OpenChannel(spacego.PreviewChannelName(mId), func() bcgo.Channel {
+		return spacego.OpenPreviewChannel(mId)
+	})
+	c.refresh(node, previews)
+	f, err := readFile(metas, node.Cache(), node.Network(), account, metaId)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil || f.deleted() {
+		return nil, c.errNotOwned(node, metaId)
+	}
+	access, err := c.identities(node, f.access())
+	if err != nil {
+		return nil, err
+	}
+	return c.writePreviews(node, listener, previews, access, f.reference(metas.Name()), preview)
+}
+
+// writePreviews writes the given previews of the file with the given meta reference into the given channel, readable by the given identities, and mines the channel.
+func (c *spaceClient) writePreviews(node bcgo.Node, listener bcgo.MiningListener, previews bcgo.Channel, access []bcgo.Identity, meta *bcgo.Reference, preview []*spacego.Preview) ([]*bcgo.Reference, error) {
+	var references []*bcgo.Reference
+	for _, p := range preview {
+		data, err := proto.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		reference, err := node.Write(bcgo.Timestamp(), previews, access, []*bcgo.Reference{meta}, data)
+		if err != nil {
+			return nil, err
+		}
+		references = append(references, reference)
+	}
+	if len(references) == 0 {
+		return nil, nil
+	}
+	var failure error
+	if err := pushed(c.mine(node, previews, listener), &failure); err != nil {
+		return nil, err
+	}
+	return references, failure
+}
+
+// AllPreviewsForHash triggers the callback with each preview of the file with the given meta ID.
+func (c *spaceClient) AllPreviewsForHash(node bcgo.Node, metaId []byte, callback spacego.PreviewCallback) error {
+	mId := base64.RawURLEncoding.EncodeToString(metaId)
+	previews := node.OpenChannel(spacego.PreviewChannelName(mId), func() bcgo.Channel {
+		return spacego.OpenPreviewChannel(mId)
+	})
+	c.refresh(node, previews)
+	return spacego.ReadPreview(previews, node.Cache(), node.Network(), node.Account(), nil, func(entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+		for _, reference := range entry.Record.Reference {
+			if bytes.Equal(metaId, reference.RecordHash) {
+				return callback(entry, preview)
+			}
+		}
+		return nil
+	})
+}
+
+// previewSource returns the maximum number of bytes of a file with the given MIME type needed to generate a preview, or zero if previews are not supported.
+func previewSource(mime string) int {
+	switch {
+	case mime == spacego.MIME_TYPE_IMAGE_JPG, mime == spacego.MIME_TYPE_IMAGE_JPEG, mime == spacego.MIME_TYPE_IMAGE_PNG:
+		return PREVIEW_IMAGE_SOURCE_LIMIT
+	case strings.HasPrefix(mime, "text/"):
+		return PREVIEW_TEXT_SOURCE_LIMIT
+	}
+	return 0
+}
+
+// generatePreviews returns previews of the given content of a file with the given MIME type.
+// Complete is false if the content was truncated.
+func generatePreviews(mime string, data []byte, complete bool) ([]*spacego.Preview, error) {
+	switch {
+	case mime == spacego.MIME_TYPE_IMAGE_JPG, mime == spacego.MIME_TYPE_IMAGE_JPEG, mime == spacego.MIME_TYPE_IMAGE_PNG:
+		if !complete {
+			return nil, nil
+		}
+		p, err := imagePreview(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*spacego.Preview{p}, nil
+	case strings.HasPrefix(mime, "text/"):
+		return []*spacego.Preview{textPreview(data)}, nil
+	}
+	return nil, nil
+}
+
+// textPreview returns a preview of the first lines of the given text.
+func textPreview(data []byte) *spacego.Preview {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for len(lines) < PREVIEW_TEXT_LINES && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return &spacego.Preview{
+		Type: spacego.MIME_TYPE_TEXT_PLAIN,
+		Data: []byte(strings.Join(lines, "\n")),
+	}
+}
+
+// imagePreview returns a preview of the given image scaled to fit within PREVIEW_IMAGE_SIZE.
+func imagePreview(data []byte) (*spacego.Preview, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if pixels := uint64(config.Width) * uint64(config.Height); pixels > PREVIEW_IMAGE_PIXEL_LIMIT {
+		return nil, fmt.Errorf("Image too large: %dx%d", config.Width, config.Height)
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	dst := scaleImage(src, PREVIEW_IMAGE_SIZE)
+	var buffer bytes.Buffer
+	mime := spacego.MIME_TYPE_IMAGE_JPEG
+	if format == "png" {
+		// Keep transparency
+		mime = spacego.MIME_TYPE_IMAGE_PNG
+		err = png.Encode(&buffer, dst)
+	} else {
+		err = jpeg.Encode(&buffer, dst, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	bounds := dst.Bounds()
+	return &spacego.Preview{
+		Type:   mime,
+		Data:   buffer.Bytes(),
+		Width:  uint32(bounds.Dx()),
+		Height: uint32(bounds.Dy()),
+	}, nil
+}
+
+// scaleImage returns the given image scaled down, preserving aspect ratio, to fit within the given size by averaging the pixels covered by each pixel of the result.
+func scaleImage(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	dw, dh := size, size
+	if w > h {
+		dh = h * size / w
+	} else {
+		dw = w * size / h
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+	dst := image.NewRGBA64(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := bounds.Min.Y + y*h/dh
+		y1 := bounds.Min.Y + (y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0 := bounds.Min.X + x*w/dw
+			x1 := bounds.Min.X + (x+1)*w/dw
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					sr, sg, sb, sa := src.At(sx, sy).RGBA()
+					r += uint64(sr)
+					g += uint64(sg)
+					b += uint64(sb)
+					a += uint64(sa)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// previewBuffer holds up to limit bytes written to it, recording whether any were discarded.
+type previewBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *previewBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); len(p) > remaining {
+		b.truncated = true
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,348 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"io"
+	"sort"
+	"sync"
+)
+
+// piece is a range of bytes inserted by a delta record.
+type piece struct {
+	channel bcgo.Channel
+	block   []byte
+	record  []byte
+	offset  uint64
+	length  uint64
+}
+
+// fileReader reads a file laid out as a sequence of pieces, loading the bytes of each piece only when read.
+type fileReader struct {
+	sync.Mutex
+	node   bcgo.Node
+	deltas bcgo.Channel
+	// decompress decompresses the bytes inserted by a delta.
+	decompress func(*spacego.Delta) error
+	pieces     []*piece
+	size       int64
+	offset     int64
+	record     []byte
+	insert     []byte
+	// inserts holds the bytes inserted by each record, kept from computing the layout while their total is within the retain limit.
+	inserts  map[string][]byte
+	retained uint64
+}
+
+// openFile computes the layout of the file with the given meta ID from the offsets and lengths of its deltas, recording the block holding each delta so its bytes can be loaded directly.
+// Inserted bytes totalling at most retain are kept in memory so reading them does not decrypt and decompress their deltas again.
+func (c *spaceClient) openFile(node bcgo.Node, metaId []byte, retain uint64) (*fileReader, error) {
+	r := &fileReader{
+		node:    node,
+		inserts: make(map[string][]byte),
+	}
+	type update struct {
+		timestamp uint64
+		block     []byte
+		record    []byte
+		offset    uint64
+		delete    uint64
+		insert    uint64
+		forward   string
+	}
+	deltas, err := c.followDeltas(node, metaId, func(deltas bcgo.Channel, compression string) (string, error) {
+		r.decompress = func(delta *spacego.Delta) error {
+			return c.decompressDelta(compression, delta)
+		}
+		head := deltas.Head()
+		if head == nil {
+			return "", nil
+		}
+		blocks := make(map[string][]byte)
+		if err := bcgo.Iterate(deltas.Name(), head, nil, node.Cache(), node.Network(), func(hash []byte, block *bcgo.Block) error {
+			for _, e := range block.Entry {
+				blocks[string(e.RecordHash)] = hash
+			}
+			return nil
+		}); err != nil {
+			return "", err
+		}
+		var updates []*update
+		if err := bcgo.Read(deltas.Name(), head, nil, node.Cache(), node.Network(), node.Account(), nil, func(entry *bcgo.BlockEntry, key, data []byte) error {
+			delta := &spacego.Delta{}
+			if err := proto.Unmarshal(data, delta); err != nil {
+				return err
+			}
+			if err := r.decompress(delta); err != nil {
+				return err
+			}
+			updates = append(updates, &update{
+				timestamp: entry.Record.Timestamp,
+				block:     blocks[string(entry.RecordHash)],
+				record:    entry.RecordHash,
+				offset:    delta.Offset,
+				delete:    delta.Delete,
+				insert:    uint64(len(delta.Insert)),
+				forward:   forward(deltas, entry),
+			})
+			r.retain(entry.RecordHash, delta.Insert, retain)
+			return nil
+		}); err != nil {
+			return "", err
+		}
+		sort.SliceStable(updates, func(i, j int) bool {
+			return updates[i].timestamp < updates[j].timestamp
+		})
+		for _, u := range updates {
+			r.apply(deltas, u.block, u.record, u.offset, u.delete, u.insert)
+			if u.forward != "" {
+				// Remaining deltas in this channel are superseded
+				return u.forward, nil
+			}
+		}
+		return "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.deltas = deltas
+	return r, nil
+}
+
+// retain keeps the bytes inserted by the given record unless the total kept would exceed the given limit, in which case none are kept.
+func (r *fileReader) retain(record, insert []byte, limit uint64) {
+	if r.inserts == nil || len(insert) == 0 {
+		return
+	}
+	r.retained += uint64(len(insert))
+	if r.retained > limit {
+		r.inserts = nil
+		return
+	}
+	r.inserts[string(record)] = insert
+}
+
+// apply updates the layout with a delta whose inserted bytes are held by the given record in the given block.
+func (r *fileReader) apply(channel bcgo.Channel, block, record []byte, offset, delete, insert uint64) {
+	size := uint64(r.size)
+	if offset > size {
+		offset = size
+	}
+	end := offset + delete
+	if end > size {
+		end = size
+	}
+	i := r.split(offset)
+	j := r.split(end)
+	pieces := append([]*piece{}, r.pieces[:i]...)
+	if insert > 0 {
+		pieces = append(pieces, &piece{
+			channel: channel,
+			block:   block,
+			record:  record,
+			length:  insert,
+		})
+	}
+	r.pieces = append(pieces, r.pieces[j:]...)
+	r.size = int64(size - (end - offset) + insert)
+}
+
+// split ensures a piece starts at the given position, and returns its index.
+func (r *fileReader) split(position uint64) int {
+	var start uint64
+	for i, p := range r.pieces {
+		if position == start {
+			return i
+		}
+		if position < start+p.length {
+			length := position - start
+			head := &piece{
+				channel: p.channel,
+				block:   p.block,
+				record:  p.record,
+				offset:  p.offset,
+				length:  length,
+			}
+			tail := &piece{
+				channel: p.channel,
+				block:   p.block,
+				record:  p.record,
+				offset:  p.offset + length,
+				length:  p.length - length,
+			}
+			r.pieces = append(r.pieces[:i], append([]*piece{head, tail}, r.pieces[i+1:]...)...)
+			return i + 1
+		}
+		start += p.length
+	}
+	return len(r.pieces)
+}
+
+// load returns the bytes inserted by the record holding the given piece, read from the block holding the record.
+func (r *fileReader) load(p *piece) ([]byte, error) {
+	if r.record != nil && bytes.Equal(r.record, p.record) {
+		return r.insert, nil
+	}
+	if insert, ok := r.inserts[string(p.record)]; ok {
+		return insert, nil
+	}
+	var insert []byte
+	found := false
+	if err := bcgo.Read(p.channel.Name(), p.block, nil, r.node.Cache(), r.node.Network(), r.node.Account(), p.record, func(entry *bcgo.BlockEntry, key, data []byte) error {
+		delta := &spacego.Delta{}
+		if err := proto.Unmarshal(data, delta); err != nil {
+			return err
+		}
+		if err := r.decompress(delta); err != nil {
+			return err
+		}
+		insert = delta.Insert
+		found = true
+		// Record is only held by this block
+		return errStopped
+	}); err != nil && err != errStopped {
+		return nil, err
+	}
+	if !found || uint64(len(insert)) < p.offset+p.length {
+		return nil, fmt.Errorf("Could not load delta from %s", p.channel.Name())
+	}
+	r.record = p.record
+	r.insert = insert
+	return insert, nil
+}
+
+// Size returns the length of the file.
+func (r *fileReader) Size() int64 {
+	return r.size
+}
+
+func (r *fileReader) ReadAt(b []byte, offset int64) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+	if offset < 0 {
+		return 0, errors.New("Negative offset")
+	}
+	if offset >= r.size {
+		return 0, io.EOF
+	}
+	var (
+		count int
+		start int64
+	)
+	for _, p := range r.pieces {
+		if count == len(b) {
+			break
+		}
+		end := start + int64(p.length)
+		if position := offset + int64(count); position < end {
+			insert, err := r.load(p)
+			if err != nil {
+				return count, err
+			}
+			from := p.offset + uint64(position-start)
+			count += copy(b[count:], insert[from:p.offset+p.length])
+		}
+		start = end
+	}
+	if count < len(b) {
+		return count, io.EOF
+	}
+	return count, nil
+}
+
+func (r *fileReader) Read(b []byte) (int, error) {
+	count, err := r.ReadAt(b, r.offset)
+	r.offset += int64(count)
+	if count > 0 && err == io.EOF {
+		err = nil
+	}
+	return count, err
+}
+
+func (r *fileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("Invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("Negative position")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *fileReader) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	r.pieces = nil
+	r.record = nil
+	r.insert = nil
+	r.inserts = nil
+	return nil
+}
+
+// content is the readable content of a file.
+type content interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+	Size() int64
+}
+
+// memoryContent is the content of a file held in memory.
+type memoryContent struct {
+	*bytes.Reader
+}
+
+func (m *memoryContent) Close() error {
+	return nil
+}
+
+// open returns the content of the file with the given meta ID.
+// Files small enough to be cached are held in memory, others are read lazily.
+func (c *spaceClient) open(node bcgo.Node, metaId []byte) (content, error) {
+	if data, ok := c.cachedContent(node, metaId); ok {
+		return &memoryContent{bytes.NewReader(data)}, nil
+	}
+	limit := c.contentCache.Limit()
+	r, err := c.openFile(node, metaId, limit)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(r.Size()) > limit {
+		return r, nil
+	}
+	defer r.Close()
+	data := make([]byte, r.Size())
+	if _, err := r.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err := c.putContent(node, metaId, r.deltas, data); err != nil {
+		c.logger.Warn("Could not cache content", "error", err)
+	}
+	return &memoryContent{bytes.NewReader(data)}, nil
+}
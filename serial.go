@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"reflect"
+	"sync"
+)
+
+// serialNode is a node shared by concurrent workers which hold its lock while they open channels, use the cache, and mine.
+// The lock is released while fetching from and pushing to peers, so workers only wait on peers concurrently.
+type serialNode struct {
+	bcgo.Node
+	lock *sync.Mutex
+}
+
+func (n *serialNode) Network() bcgo.Network {
+	network := n.Node.Network()
+	if network == nil || reflect.ValueOf(network).IsNil() {
+		return nil
+	}
+	return &serialNetwork{
+		Network: network,
+		lock:    n.lock,
+	}
+}
+
+// serialNetwork is a network which releases the lock of a serialNode while fetching from and pushing to peers.
+type serialNetwork struct {
+	bcgo.Network
+	lock *sync.Mutex
+}
+
+func (n *serialNetwork) Head(channel string) (*bcgo.Reference, error) {
+	n.lock.Unlock()
+	defer n.lock.Lock()
+	return n.Network.Head(channel)
+}
+
+func (n *serialNetwork) Block(reference *bcgo.Reference) (*bcgo.Block, error) {
+	n.lock.Unlock()
+	defer n.lock.Lock()
+	return n.Network.Block(reference)
+}
+
+// Broadcast pushes the given block without holding the lock, which is taken again whenever the network reads an earlier block from the cache to send to peers missing it.
+func (n *serialNetwork) Broadcast(channel bcgo.Channel, cache bcgo.Cache, hash []byte, block *bcgo.Block) error {
+	n.lock.Unlock()
+	defer n.lock.Lock()
+	return n.Network.Broadcast(channel, &serialCache{
+		Cache: cache,
+		lock:  n.lock,
+	}, hash, block)
+}
+
+// serialCache is a cache which holds the lock of a serialNode while reading blocks, used by broadcasts made without the lock.
+type serialCache struct {
+	bcgo.Cache
+	lock *sync.Mutex
+}
+
+func (c *serialCache) Block(hash []byte) (*bcgo.Block, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Cache.Block(hash)
+}
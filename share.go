@@ -0,0 +1,555 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/aliasgo"
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/golang/protobuf/proto"
+)
+
+// Share grants the given aliases access to the file with the given meta ID.
+//
+// Records are immutable so existing records cannot be re-encrypted, instead
+// Share writes a new version of the meta data readable by everyone with
+// access, a snapshot of the file and a copy of its tags and previews readable
+// only by the new aliases, and a reference to the file into each new alias' meta channel.
+// Subsequent changes to the file are readable by all aliases with access.
+func (c *spaceClient) Share(node bcgo.Node, listener bcgo.MiningListener, metaId []byte, aliases ...string) error {
+	account := node.Account()
+	alias := account.Alias()
+	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	c.refresh(node, metas)
+	mId := base64.RawURLEncoding.EncodeToString(metaId)
+	f, err := readFile(metas, node.Cache(), node.Network(), account, metaId)
+	if err != nil {
+		return err
+	}
+	if f == nil || f.deleted() {
+		return c.errNotOwned(node, metaId)
+	}
+
+	granted := f.access()
+	var added []string
+	for _, a := range aliases {
+		if !containsAlias(granted, a) && !containsAlias(added, a) {
+			added = append(added, a)
+		}
+	}
+	if len(added) == 0 {
+		// Nothing to share
+		return nil
+	}
+
+	access, err := c.identities(node, append(granted, added...))
+	if err != nil {
+		return err
+	}
+	recipients := access[len(granted):]
+	reference := f.reference(metas.Name())
+
+	// Write a new version of the meta data readable by all
+	data, err := f.marshal(f.latest().meta)
+	if err != nil {
+		return err
+	}
+	if _, err := node.Write(bcgo.Timestamp(), metas, access, []*bcgo.Reference{reference}, data); err != nil {
+		return err
+	}
+	// Push failures are reported once the file is shared
+	var failure error
+	if err := pushed(c.mine(node, metas, listener), &failure); err != nil {
+		return err
+	}
+
+	// Write a snapshot of the file readable by the recipients
+	buffer := []byte{}
+	deltas, err := c.iterateDeltas(node, metaId, func(channel bcgo.Channel, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		buffer = spacego.ApplyDelta(delta, buffer)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(buffer) > 0 {
+		if _, err := c.writeSnapshot(node, deltas, recipients, f.compression(), buffer); err != nil {
+			return err
+		}
+		if err := pushed(c.mine(node, deltas, listener), &failure); err != nil {
+			return err
+		}
+	}
+
+	// Write a copy of the tags readable by the recipients
+	tags := node.OpenChannel(spacego.TagChannelName(mId), func() bcgo.Channel {
+		return spacego.OpenTagChannel(mId)
+	})
+	var count int
+	if err := c.AllTagsForHash(node, metaId, func(entry *bcgo.BlockEntry, tag *spacego.Tag) error {
+		data, err := proto.Marshal(tag)
+		if err != nil {
+			return err
+		}
+		if _, err := node.Write(bcgo.Timestamp(), tags, recipients, []*bcgo.Reference{reference}, data); err != nil {
+			return err
+		}
+		count++
+		return nil
+	}); err != nil {
+		return err
+	}
+	if count > 0 {
+		if err := pushed(c.mine(node, tags, listener), &failure); err != nil {
+			return err
+		}
+	}
+
+	// Write a copy of the previews readable by the recipients
+	previews := node.OpenChannel(spacego.PreviewChannelName(mId), func() bcgo.Channel {
+		return spacego.OpenPreviewChannel(mId)
+	})
+	count = 0
+	if err := c.AllPreviewsForHash(node, metaId, func(entry *bcgo.BlockEntry, preview *spacego.Preview) error {
+		data, err := proto.Marshal(preview)
+		if err != nil {
+			return err
+		}
+		if _, err := node.Write(bcgo.Timestamp(), previews, recipients, []*bcgo.Reference{reference}, data); err != nil {
+			return err
+		}
+		count++
+		return nil
+	}); err != nil {
+		return err
+	}
+	if count > 0 {
+		if err := pushed(c.mine(node, previews, listener), &failure); err != nil {
+			return err
+		}
+	}
+
+	// Notify each recipient by writing a reference into their meta channel
+	for _, recipient := range recipients {
+		a := recipient.Alias()
+		inbox := node.OpenChannel(spacego.MetaChannelName(a), func() bcgo.Channel {
+			return spacego.OpenMetaChannel(a)
+		})
+		c.refresh(node, inbox)
+		if _, err := node.Write(bcgo.Timestamp(), inbox, []bcgo.Identity{recipient}, []*bcgo.Reference{reference}, data); err != nil {
+			return err
+		}
+		if err := pushed(c.mine(node, inbox, listener), &failure); err != nil {
+			return err
+		}
+	}
+	return failure
+}
+
+// Revoke removes the given aliases' access to the file with the given meta ID.
+//
+// Revoke writes a snapshot of the file into a new delta channel readable only
+// by the remaining aliases, forwards the file from the current delta channel
+// to the new one, and writes a new version of the meta data referencing it.
+// Records are immutable so aliases which have been revoked can still decrypt
+// the records they were granted, but as the latest version of the meta data no
+// longer grants them access the file is no longer listed as shared with them,
+// nor read for them by the client, and subsequent changes are not readable by them.
+func (c *spaceClient) Revoke(node bcgo.Node, listener bcgo.MiningListener, metaId []byte, aliases ...string) error {
+	account := node.Account()
+	alias := account.Alias()
+	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	c.refresh(node, metas)
+	f, err := readFile(metas, node.Cache(), node.Network(), account, metaId)
+	if err != nil {
+		return err
+	}
+	if f == nil || f.deleted() {
+		return c.errNotOwned(node, metaId)
+	}
+
+	granted := f.access()
+	var remaining []string
+	for _, a := range granted {
+		if a == alias || !containsAlias(aliases, a) {
+			remaining = append(remaining, a)
+		}
+	}
+	if len(remaining) == len(granted) {
+		// Nothing to revoke
+		return nil
+	}
+
+	access, err := c.identities(node, remaining)
+	if err != nil {
+		return err
+	}
+
+	// Read current file
+	buffer := []byte{}
+	current, err := c.iterateDeltas(node, metaId, func(channel bcgo.Channel, entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		buffer = spacego.ApplyDelta(delta, buffer)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write a snapshot of the file into a new channel
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	dId := base64.RawURLEncoding.EncodeToString(id)
+	deltas := node.OpenChannel(spacego.DeltaChannelName(dId), func() bcgo.Channel {
+		return spacego.OpenDeltaChannel(dId)
+	})
+	snapshot, err := c.writeSnapshot(node, deltas, access, f.compression(), buffer)
+	if err != nil {
+		return err
+	}
+	// Push failures are reported once access is revoked
+	var failure error
+	if err := pushed(c.mine(node, deltas, listener), &failure); err != nil {
+		return err
+	}
+
+	// Forward the file from the current channel to the new channel, once the whole snapshot is written
+	data, err := proto.Marshal(&spacego.Delta{
+		Delete: uint64(len(buffer)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := node.Write(bcgo.Timestamp(), current, access, []*bcgo.Reference{snapshot}, data); err != nil {
+		return err
+	}
+	if err := pushed(c.mine(node, current, listener), &failure); err != nil {
+		return err
+	}
+
+	// Write a new version of the meta data referencing the new channel
+	data, err = f.marshal(f.latest().meta)
+	if err != nil {
+		return err
+	}
+	if _, err := node.Write(bcgo.Timestamp(), metas, access, []*bcgo.Reference{f.reference(metas.Name()), snapshot}, data); err != nil {
+		return err
+	}
+	if err := pushed(c.mine(node, metas, listener), &failure); err != nil {
+		return err
+	}
+	return failure
+}
+
+// writeSnapshot writes the given content into the given delta channel readable by the given identities, split into deltas within the size limit of a record and compressed with the given compression, and returns a reference to the first.
+// An empty delta is written for empty content, so there is always a record to reference.
+func (c *spaceClient) writeSnapshot(node bcgo.Node, deltas bcgo.Channel, access []bcgo.Identity, compression string, content []byte) (*bcgo.Reference, error) {
+	var first *bcgo.Reference
+	var last uint64
+	write := func(delta *spacego.Delta) error {
+		delta, err := c.compressDelta(compression, delta)
+		if err != nil {
+			return err
+		}
+		data, err := proto.Marshal(delta)
+		if err != nil {
+			return err
+		}
+		timestamp := bcgo.Timestamp()
+		// Ensure timestamp is greater than previous to ensure deltas (sorted by timestamp) don't get out of order
+		for last == timestamp {
+			timestamp = bcgo.Timestamp()
+		}
+		last = timestamp
+		reference, err := node.Write(timestamp, deltas, access, nil, data)
+		if err != nil {
+			return err
+		}
+		c.written(deltas, reference)
+		if first == nil {
+			first = reference
+		}
+		return nil
+	}
+	if err := spacego.CreateDeltas(bytes.NewReader(content), maxInsert(compression), write); err != nil {
+		return nil, err
+	}
+	if first == nil {
+		if err := write(&spacego.Delta{}); err != nil {
+			return nil, err
+		}
+	}
+	return first, nil
+}
+
+// SharedMetaCallback is triggered with the alias of the owner and the meta data of a file shared by them.
+type SharedMetaCallback func(string, *bcgo.BlockEntry, *spacego.Meta) error
+
+// SharedMetas lists files other aliases have shared with key
+func (c *spaceClient) SharedMetas(node bcgo.Node, callback SharedMetaCallback) error {
+	files, err := c.sharedFiles(node)
+	if err != nil {
+		return err
+	}
+	for _, s := range files {
+		if err := callback(s.owner, s.entry, s.meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharedFile is a file shared with the node's account by another alias.
+type sharedFile struct {
+	owner string
+	entry *bcgo.BlockEntry
+	meta  *spacego.Meta
+	file  *file
+}
+
+func newSharedFile(owner string, f *file) *sharedFile {
+	return &sharedFile{
+		owner: owner,
+		entry: f.entry(),
+		meta:  f.latest().meta,
+		file:  f,
+	}
+}
+
+// sharedPointer is a reference to a file in its owner's meta channel, written into the account's meta channel by Share.
+type sharedPointer struct {
+	owner     string
+	reference *bcgo.Reference
+}
+
+// sharedPointers holds the files shared with an account when its meta channel had the given head.
+type sharedPointers struct {
+	head     []byte
+	pointers []*sharedPointer
+}
+
+// ownerFiles holds the files of an owner readable by an account when the owner's meta channel had the given head.
+type ownerFiles struct {
+	head   []byte
+	files  map[string]*file
+	access map[string][]string
+}
+
+// shared returns the file with the given meta ID, or nil if the owner never shared it with the given alias, moved it to the trash, or revoked the alias' access.
+func (o *ownerFiles) shared(alias string, metaId []byte) *file {
+	f, ok := o.files[string(metaId)]
+	if !ok || f.deleted() || !containsAlias(o.access[string(metaId)], alias) {
+		return nil
+	}
+	return f
+}
+
+// sharedFiles returns the latest meta data of each file other aliases have shared with the node's account.
+func (c *spaceClient) sharedFiles(node bcgo.Node) ([]*sharedFile, error) {
+	alias := node.Account().Alias()
+	pointers, err := c.sharedPointers(node, c.openMetas(node))
+	if err != nil {
+		return nil, err
+	}
+	var files []*sharedFile
+	owners := make(map[string]*ownerFiles)
+	for _, p := range pointers {
+		o, ok := owners[p.owner]
+		if !ok {
+			o, err = c.ownerFiles(node, p.owner)
+			if err != nil {
+				return nil, err
+			}
+			owners[p.owner] = o
+		}
+		if f := o.shared(alias, p.reference.RecordHash); f != nil {
+			files = append(files, newSharedFile(p.owner, f))
+		}
+	}
+	return files, nil
+}
+
+// sharedFile returns the latest meta data of the file with the given meta ID shared with the node's account, or nil if no such file has been shared with it.
+// Only the meta channel of the file's owner is refreshed, the given meta channel of the account must already be refreshed.
+func (c *spaceClient) sharedFile(node bcgo.Node, metas bcgo.Channel, metaId []byte) (*sharedFile, error) {
+	pointers, err := c.sharedPointers(node, metas)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pointers {
+		if !bytes.Equal(p.reference.RecordHash, metaId) {
+			continue
+		}
+		o, err := c.ownerFiles(node, p.owner)
+		if err != nil {
+			return nil, err
+		}
+		if f := o.shared(node.Account().Alias(), metaId); f != nil {
+			return newSharedFile(p.owner, f), nil
+		}
+	}
+	return nil, nil
+}
+
+// sharedPointers returns the references to files other aliases have shared with the node's account, read from the account's given meta channel.
+// References are cached until the meta channel changes, so owners which were not registered are checked again once it does.
+func (c *spaceClient) sharedPointers(node bcgo.Node, metas bcgo.Channel) ([]*sharedPointer, error) {
+	account := node.Account()
+	alias := account.Alias()
+	head := metas.Head()
+	if v, ok := c.shared.Load(alias); ok && bytes.Equal(v.(*sharedPointers).head, head) {
+		return v.(*sharedPointers).pointers, nil
+	}
+	var pointers []*sharedPointer
+	seen := make(map[string]bool)
+	if err := spacego.ReadMeta(metas, node.Cache(), node.Network(), account, nil, func(entry *bcgo.BlockEntry, meta *spacego.Meta) error {
+		owner := entry.Record.Creator
+		if owner == alias {
+			return nil
+		}
+		for _, r := range entry.Record.Reference {
+			// Only accept references to files in the creator's own meta channel
+			if r.ChannelName != spacego.MetaChannelName(owner) || seen[string(r.RecordHash)] {
+				continue
+			}
+			seen[string(r.RecordHash)] = true
+			pointers = append(pointers, &sharedPointer{
+				owner:     owner,
+				reference: r,
+			})
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var accepted []*sharedPointer
+	registered := make(map[string]bool)
+	for _, p := range pointers {
+		owner := p.owner
+		if _, ok := registered[owner]; !ok {
+			// Only accept files shared by registered aliases
+			_, err := c.identities(node, []string{owner})
+			if err != nil {
+				c.logger.Warn("Ignoring file shared by unknown alias", "alias", owner, "error", err)
+			}
+			registered[owner] = err == nil
+		}
+		if registered[owner] {
+			accepted = append(accepted, p)
+		}
+	}
+	c.shared.Store(alias, &sharedPointers{
+		head:     head,
+		pointers: accepted,
+	})
+	return accepted, nil
+}
+
+// ownerFiles returns the files in the meta channel of the given owner which are readable by the node's account.
+// The shared meta data is only trusted if the owner's channel holds a version of the file readable by the account.
+// Files are cached until the owner's meta channel changes, so only the channel's head is fetched while it is unchanged.
+func (c *spaceClient) ownerFiles(node bcgo.Node, owner string) (*ownerFiles, error) {
+	account := node.Account()
+	channel := node.OpenChannel(spacego.MetaChannelName(owner), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(owner)
+	})
+	c.refresh(node, channel)
+	key := account.Alias() + "/" + owner
+	head := channel.Head()
+	if v, ok := c.owners.Load(key); ok && bytes.Equal(v.(*ownerFiles).head, head) {
+		return v.(*ownerFiles), nil
+	}
+	files, err := readFiles(channel, node.Cache(), node.Network(), account)
+	if err != nil {
+		return nil, err
+	}
+	access, err := latestAccess(channel, node.Cache(), node.Network())
+	if err != nil {
+		return nil, err
+	}
+	o := &ownerFiles{
+		head:   head,
+		files:  make(map[string]*file, len(files)),
+		access: access,
+	}
+	for _, f := range files {
+		o.files[string(f.id)] = f
+	}
+	c.owners.Store(key, o)
+	return o, nil
+}
+
+// identities returns the identities of the given aliases.
+func (c *spaceClient) identities(node bcgo.Node, aliases []string) ([]bcgo.Identity, error) {
+	account := node.Account()
+	var (
+		identities []bcgo.Identity
+		channel    bcgo.Channel
+	)
+	for _, a := range aliases {
+		if a == account.Alias() {
+			identities = append(identities, account)
+			continue
+		}
+		if channel == nil {
+			channel = node.OpenChannel(aliasgo.ALIAS, func() bcgo.Channel {
+				return aliasgo.OpenAliasChannel()
+			})
+			c.refresh(node, channel)
+		}
+		identity, err := aliasgo.IdentityForAlias(channel, node.Cache(), node.Network(), a)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
+
+// findFile returns the first file passing the given match which is owned by the account, or shared with it, and not in the trash.
+// Returns nil if no file matches.
+func (c *spaceClient) findFile(node bcgo.Node, match func(*file) bool) (*file, error) {
+	metas := c.openMetas(node)
+	files, err := readFiles(metas, node.Cache(), node.Network(), node.Account())
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if !f.deleted() && match(f) {
+			return f, nil
+		}
+	}
+	shared, err := c.sharedFiles(node)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range shared {
+		if match(s.file) {
+			return s.file, nil
+		}
+	}
+	return nil, nil
+}
@@ -20,6 +20,7 @@ import (
 	"aletheiaware.com/bcclientgo/test"
 	"aletheiaware.com/bcgo"
 	"aletheiaware.com/financego"
+	"aletheiaware.com/spaceclientgo"
 	"aletheiaware.com/spacego"
 	"context"
 	"io"
@@ -39,15 +40,34 @@ type MockSpaceClient struct {
 	MockName, MockMime              string
 	MockReference                   *bcgo.Reference
 	MockReferences                  []*bcgo.Reference
+	MockFileSpecs                   []spaceclientgo.FileSpec
 	MockDeltaChannel                bcgo.Channel
 	MockDeltas                      []*spacego.Delta
 	MockHash                        []byte
+	MockOffset, MockLength          int64
+	MockTimestamp                   uint64
+	MockVersion, MockToVersion      []byte
 	MockMetaFilter                  spacego.MetaFilter
 	MockMetaCallback                spacego.MetaCallback
 	MockMetaCallbackResults         []*MockMetaCallbackResult
+	MockTrashCallbackResults        []*MockMetaCallbackResult
+	MockHistoryCallback             spaceclientgo.HistoryCallback
+	MockFileChangeCallback          spaceclientgo.FileChangeCallback
+	MockFileChanges                 []*spaceclientgo.FileChange
+	MockFileWatch                   spaceclientgo.FileWatch
+	MockHistoryCallbackResults      []*MockHistoryCallbackResult
+	MockSharedMetaCallback          spaceclientgo.SharedMetaCallback
+	MockSharedMetaCallbackResults   []*MockSharedMetaCallbackResult
 	MockWriteCloser                 io.WriteCloser
+	MockReadSeekCloser              io.ReadSeekCloser
 	MockTagFilter                   spacego.TagFilter
+	MockTagCallback                 spacego.TagCallback
+	MockTagCallbackResults          []*MockTagCallbackResult
+	MockPreviews                    []*spacego.Preview
+	MockPreviewCallback             spacego.PreviewCallback
+	MockPreviewCallbackResults      []*MockPreviewCallbackResult
 	MockTags                        []string
+	MockAliases                     []string
 	MockMerchant                    string
 	MockRegistrationCallback        financego.RegistrationCallback
 	MockRegistrationCallbackResults []*MockRegistrationCallbackResult
@@ -55,9 +75,20 @@ type MockSpaceClient struct {
 	MockSubscriptionCallbackResults []*MockSubscriptionCallbackResult
 
 	MockAddError, MockAppendError                error
+	MockAddAllError                              error
 	MockMetaError, MockAllMetasError             error
 	MockReadError, MockWriteError                error
+	MockOpenError, MockHistoryError              error
+	MockWatchError                               error
+	MockDiffError, MockRevertError               error
+	MockUpdateMetaError                          error
+	MockDeleteError, MockRestoreError            error
+	MockTrashError, MockEmptyTrashError          error
 	MockAddTagError, MockAllTagsError            error
+	MockRemoveTagError                           error
+	MockAddPreviewError, MockAllPreviewsError    error
+	MockShareError, MockSharedMetasError         error
+	MockRevokeError                              error
 	MockSearchMetaError, MockSearchTagError      error
 	MockRegistrationError, MockSubscriptionError error
 }
@@ -71,6 +102,13 @@ func (c *MockSpaceClient) Add(node bcgo.Node, listener bcgo.MiningListener, name
 	return c.MockReference, c.MockAddError
 }
 
+func (c *MockSpaceClient) AddAll(node bcgo.Node, listener bcgo.MiningListener, files []spaceclientgo.FileSpec) ([]*bcgo.Reference, error) {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockFileSpecs = files
+	return c.MockReferences, c.MockAddAllError
+}
+
 func (c *MockSpaceClient) Amend(node bcgo.Node, listener bcgo.MiningListener, channel bcgo.Channel, deltas ...*spacego.Delta) error {
 	c.MockNode = node
 	c.MockListener = listener
@@ -79,6 +117,15 @@ func (c *MockSpaceClient) Amend(node bcgo.Node, listener bcgo.MiningListener, ch
 	return c.MockAppendError
 }
 
+func (c *MockSpaceClient) UpdateMeta(node bcgo.Node, listener bcgo.MiningListener, hash []byte, name, mime string) error {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockHash = hash
+	c.MockName = name
+	c.MockMime = mime
+	return c.MockUpdateMetaError
+}
+
 func (c *MockSpaceClient) MetaForHash(node bcgo.Node, hash []byte, callback spacego.MetaCallback) error {
 	c.MockNode = node
 	c.MockHash = hash
@@ -104,6 +151,62 @@ func (c *MockSpaceClient) ReadFile(node bcgo.Node, hash []byte) (io.Reader, erro
 	return c.MockReader, c.MockReadError
 }
 
+func (c *MockSpaceClient) OpenFile(node bcgo.Node, hash []byte) (io.ReadSeekCloser, error) {
+	c.MockNode = node
+	c.MockHash = hash
+	return c.MockReadSeekCloser, c.MockOpenError
+}
+
+func (c *MockSpaceClient) ReadFileRange(node bcgo.Node, hash []byte, offset, length int64) (io.Reader, error) {
+	c.MockNode = node
+	c.MockHash = hash
+	c.MockOffset = offset
+	c.MockLength = length
+	return c.MockReader, c.MockReadError
+}
+
+func (c *MockSpaceClient) ReadFileAt(node bcgo.Node, hash []byte, timestamp uint64) (io.Reader, error) {
+	c.MockNode = node
+	c.MockHash = hash
+	c.MockTimestamp = timestamp
+	return c.MockReader, c.MockReadError
+}
+
+func (c *MockSpaceClient) ReadFileVersion(node bcgo.Node, hash, version []byte) (io.Reader, error) {
+	c.MockNode = node
+	c.MockHash = hash
+	c.MockVersion = version
+	return c.MockReader, c.MockReadError
+}
+
+func (c *MockSpaceClient) History(node bcgo.Node, hash []byte, callback spaceclientgo.HistoryCallback) error {
+	c.MockNode = node
+	c.MockHash = hash
+	c.MockHistoryCallback = callback
+	for _, r := range c.MockHistoryCallbackResults {
+		if err := callback(r.Block, r.Entry, r.Delta); err != nil {
+			return err
+		}
+	}
+	return c.MockHistoryError
+}
+
+func (c *MockSpaceClient) Revert(node bcgo.Node, listener bcgo.MiningListener, hash, version []byte) error {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockHash = hash
+	c.MockVersion = version
+	return c.MockRevertError
+}
+
+func (c *MockSpaceClient) Diff(node bcgo.Node, hash, from, to []byte) (io.Reader, error) {
+	c.MockNode = node
+	c.MockHash = hash
+	c.MockVersion = from
+	c.MockToVersion = to
+	return c.MockReader, c.MockDiffError
+}
+
 func (c *MockSpaceClient) WriteFile(node bcgo.Node, listener bcgo.MiningListener, hash []byte) (io.WriteCloser, error) {
 	c.MockNode = node
 	c.MockListener = listener
@@ -117,6 +220,46 @@ func (c *MockSpaceClient) WatchFile(ctx context.Context, node bcgo.Node, hash []
 	c.MockHash = hash
 }
 
+func (c *MockSpaceClient) WatchFileChanges(ctx context.Context, node bcgo.Node, hash []byte, callback spaceclientgo.FileChangeCallback) (spaceclientgo.FileWatch, error) {
+	c.MockContext = ctx
+	c.MockNode = node
+	c.MockHash = hash
+	c.MockFileChangeCallback = callback
+	for _, change := range c.MockFileChanges {
+		callback(change)
+	}
+	return c.MockFileWatch, c.MockWatchError
+}
+
+func (c *MockSpaceClient) WatchMetas(ctx context.Context, node bcgo.Node, callback spacego.MetaCallback) {
+	c.MockContext = ctx
+	c.MockNode = node
+	c.MockMetaCallback = callback
+	for _, r := range c.MockMetaCallbackResults {
+		callback(r.Entry, r.Meta)
+	}
+}
+
+func (c *MockSpaceClient) AddPreview(node bcgo.Node, listener bcgo.MiningListener, hash []byte, previews []*spacego.Preview) ([]*bcgo.Reference, error) {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockHash = hash
+	c.MockPreviews = previews
+	return c.MockReferences, c.MockAddPreviewError
+}
+
+func (c *MockSpaceClient) AllPreviewsForHash(node bcgo.Node, hash []byte, callback spacego.PreviewCallback) error {
+	c.MockNode = node
+	c.MockHash = hash
+	c.MockPreviewCallback = callback
+	for _, r := range c.MockPreviewCallbackResults {
+		if err := callback(r.Entry, r.Preview); err != nil {
+			return err
+		}
+	}
+	return c.MockAllPreviewsError
+}
+
 func (c *MockSpaceClient) AddTag(node bcgo.Node, listener bcgo.MiningListener, hash []byte, tags []string) ([]*bcgo.Reference, error) {
 	c.MockNode = node
 	c.MockListener = listener
@@ -125,12 +268,90 @@ func (c *MockSpaceClient) AddTag(node bcgo.Node, listener bcgo.MiningListener, h
 	return c.MockReferences, c.MockAddTagError
 }
 
+func (c *MockSpaceClient) WatchTags(ctx context.Context, node bcgo.Node, hash []byte, callback spacego.TagCallback) {
+	c.MockContext = ctx
+	c.MockNode = node
+	c.MockHash = hash
+	c.MockTagCallback = callback
+	for _, r := range c.MockTagCallbackResults {
+		callback(r.Entry, r.Tag)
+	}
+}
+
+func (c *MockSpaceClient) RemoveTag(node bcgo.Node, listener bcgo.MiningListener, hash []byte, tags []string) ([]*bcgo.Reference, error) {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockHash = hash
+	c.MockTags = tags
+	return c.MockReferences, c.MockRemoveTagError
+}
+
 func (c *MockSpaceClient) AllTagsForHash(node bcgo.Node, hash []byte, callback spacego.TagCallback) error {
 	c.MockNode = node
 	c.MockHash = hash
+	c.MockTagCallback = callback
+	for _, r := range c.MockTagCallbackResults {
+		callback(r.Entry, r.Tag)
+	}
 	return c.MockAllTagsError
 }
 
+func (c *MockSpaceClient) Share(node bcgo.Node, listener bcgo.MiningListener, hash []byte, aliases ...string) error {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockHash = hash
+	c.MockAliases = aliases
+	return c.MockShareError
+}
+
+func (c *MockSpaceClient) SharedMetas(node bcgo.Node, callback spaceclientgo.SharedMetaCallback) error {
+	c.MockNode = node
+	c.MockSharedMetaCallback = callback
+	for _, r := range c.MockSharedMetaCallbackResults {
+		callback(r.Owner, r.Entry, r.Meta)
+	}
+	return c.MockSharedMetasError
+}
+
+func (c *MockSpaceClient) Revoke(node bcgo.Node, listener bcgo.MiningListener, hash []byte, aliases ...string) error {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockHash = hash
+	c.MockAliases = aliases
+	return c.MockRevokeError
+}
+
+func (c *MockSpaceClient) Delete(node bcgo.Node, listener bcgo.MiningListener, hash []byte) error {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockHash = hash
+	return c.MockDeleteError
+}
+
+func (c *MockSpaceClient) Restore(node bcgo.Node, listener bcgo.MiningListener, hash []byte) error {
+	c.MockNode = node
+	c.MockListener = listener
+	c.MockHash = hash
+	return c.MockRestoreError
+}
+
+func (c *MockSpaceClient) Trash(node bcgo.Node, callback spacego.MetaCallback) error {
+	c.MockNode = node
+	c.MockMetaCallback = callback
+	for _, r := range c.MockTrashCallbackResults {
+		if err := callback(r.Entry, r.Meta); err != nil {
+			return err
+		}
+	}
+	return c.MockTrashError
+}
+
+func (c *MockSpaceClient) EmptyTrash(node bcgo.Node, listener bcgo.MiningListener) error {
+	c.MockNode = node
+	c.MockListener = listener
+	return c.MockEmptyTrashError
+}
+
 func (c *MockSpaceClient) SearchMeta(node bcgo.Node, filter spacego.MetaFilter, callback spacego.MetaCallback) error {
 	c.MockNode = node
 	c.MockMetaFilter = filter
@@ -169,11 +390,188 @@ func (c *MockSpaceClient) Subscription(merchant string, callback financego.Subsc
 	return c.MockSubscriptionError
 }
 
+func (c *MockSpaceClient) AddContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, name, mime string, reader io.Reader) (*bcgo.Reference, error) {
+	c.MockContext = ctx
+	return c.Add(node, listener, name, mime, reader)
+}
+
+func (c *MockSpaceClient) AddAllContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, files []spaceclientgo.FileSpec) ([]*bcgo.Reference, error) {
+	c.MockContext = ctx
+	return c.AddAll(node, listener, files)
+}
+
+func (c *MockSpaceClient) AmendContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, channel bcgo.Channel, deltas ...*spacego.Delta) error {
+	c.MockContext = ctx
+	return c.Amend(node, listener, channel, deltas...)
+}
+
+func (c *MockSpaceClient) UpdateMetaContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, name, mime string) error {
+	c.MockContext = ctx
+	return c.UpdateMeta(node, listener, metaId, name, mime)
+}
+
+func (c *MockSpaceClient) MetaForHashContext(ctx context.Context, node bcgo.Node, metaId []byte, callback spacego.MetaCallback) error {
+	c.MockContext = ctx
+	return c.MetaForHash(node, metaId, callback)
+}
+
+func (c *MockSpaceClient) AllMetasContext(ctx context.Context, node bcgo.Node, callback spacego.MetaCallback) error {
+	c.MockContext = ctx
+	return c.AllMetas(node, callback)
+}
+
+func (c *MockSpaceClient) ReadFileContext(ctx context.Context, node bcgo.Node, metaId []byte) (io.Reader, error) {
+	c.MockContext = ctx
+	return c.ReadFile(node, metaId)
+}
+
+func (c *MockSpaceClient) OpenFileContext(ctx context.Context, node bcgo.Node, metaId []byte) (io.ReadSeekCloser, error) {
+	c.MockContext = ctx
+	return c.OpenFile(node, metaId)
+}
+
+func (c *MockSpaceClient) ReadFileRangeContext(ctx context.Context, node bcgo.Node, metaId []byte, offset, length int64) (io.Reader, error) {
+	c.MockContext = ctx
+	return c.ReadFileRange(node, metaId, offset, length)
+}
+
+func (c *MockSpaceClient) ReadFileAtContext(ctx context.Context, node bcgo.Node, metaId []byte, timestamp uint64) (io.Reader, error) {
+	c.MockContext = ctx
+	return c.ReadFileAt(node, metaId, timestamp)
+}
+
+func (c *MockSpaceClient) ReadFileVersionContext(ctx context.Context, node bcgo.Node, metaId, recordHash []byte) (io.Reader, error) {
+	c.MockContext = ctx
+	return c.ReadFileVersion(node, metaId, recordHash)
+}
+
+func (c *MockSpaceClient) HistoryContext(ctx context.Context, node bcgo.Node, metaId []byte, callback spaceclientgo.HistoryCallback) error {
+	c.MockContext = ctx
+	return c.History(node, metaId, callback)
+}
+
+func (c *MockSpaceClient) DiffContext(ctx context.Context, node bcgo.Node, metaId, fromRecord, toRecord []byte) (io.Reader, error) {
+	c.MockContext = ctx
+	return c.Diff(node, metaId, fromRecord, toRecord)
+}
+
+func (c *MockSpaceClient) WriteFileContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte) (io.WriteCloser, error) {
+	c.MockContext = ctx
+	return c.WriteFile(node, listener, metaId)
+}
+
+func (c *MockSpaceClient) RevertContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId, recordHash []byte) error {
+	c.MockContext = ctx
+	return c.Revert(node, listener, metaId, recordHash)
+}
+
+func (c *MockSpaceClient) AddPreviewContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, preview []*spacego.Preview) ([]*bcgo.Reference, error) {
+	c.MockContext = ctx
+	return c.AddPreview(node, listener, metaId, preview)
+}
+
+func (c *MockSpaceClient) AllPreviewsForHashContext(ctx context.Context, node bcgo.Node, metaId []byte, callback spacego.PreviewCallback) error {
+	c.MockContext = ctx
+	return c.AllPreviewsForHash(node, metaId, callback)
+}
+
+func (c *MockSpaceClient) AddTagContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, tag []string) ([]*bcgo.Reference, error) {
+	c.MockContext = ctx
+	return c.AddTag(node, listener, metaId, tag)
+}
+
+func (c *MockSpaceClient) AllTagsForHashContext(ctx context.Context, node bcgo.Node, metaId []byte, callback spacego.TagCallback) error {
+	c.MockContext = ctx
+	return c.AllTagsForHash(node, metaId, callback)
+}
+
+func (c *MockSpaceClient) RemoveTagContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, tag []string) ([]*bcgo.Reference, error) {
+	c.MockContext = ctx
+	return c.RemoveTag(node, listener, metaId, tag)
+}
+
+func (c *MockSpaceClient) ShareContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, aliases ...string) error {
+	c.MockContext = ctx
+	return c.Share(node, listener, metaId, aliases...)
+}
+
+func (c *MockSpaceClient) SharedMetasContext(ctx context.Context, node bcgo.Node, callback spaceclientgo.SharedMetaCallback) error {
+	c.MockContext = ctx
+	return c.SharedMetas(node, callback)
+}
+
+func (c *MockSpaceClient) RevokeContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte, aliases ...string) error {
+	c.MockContext = ctx
+	return c.Revoke(node, listener, metaId, aliases...)
+}
+
+func (c *MockSpaceClient) DeleteContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte) error {
+	c.MockContext = ctx
+	return c.Delete(node, listener, metaId)
+}
+
+func (c *MockSpaceClient) RestoreContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener, metaId []byte) error {
+	c.MockContext = ctx
+	return c.Restore(node, listener, metaId)
+}
+
+func (c *MockSpaceClient) TrashContext(ctx context.Context, node bcgo.Node, callback spacego.MetaCallback) error {
+	c.MockContext = ctx
+	return c.Trash(node, callback)
+}
+
+func (c *MockSpaceClient) EmptyTrashContext(ctx context.Context, node bcgo.Node, listener bcgo.MiningListener) error {
+	c.MockContext = ctx
+	return c.EmptyTrash(node, listener)
+}
+
+func (c *MockSpaceClient) SearchMetaContext(ctx context.Context, node bcgo.Node, filter spacego.MetaFilter, callback spacego.MetaCallback) error {
+	c.MockContext = ctx
+	return c.SearchMeta(node, filter, callback)
+}
+
+func (c *MockSpaceClient) SearchTagContext(ctx context.Context, node bcgo.Node, filter spacego.TagFilter, callback spacego.MetaCallback) error {
+	c.MockContext = ctx
+	return c.SearchTag(node, filter, callback)
+}
+
+func (c *MockSpaceClient) RegistrationContext(ctx context.Context, merchant string, callback financego.RegistrationCallback) error {
+	c.MockContext = ctx
+	return c.Registration(merchant, callback)
+}
+
+func (c *MockSpaceClient) SubscriptionContext(ctx context.Context, merchant string, callback financego.SubscriptionCallback) error {
+	c.MockContext = ctx
+	return c.Subscription(merchant, callback)
+}
+
 type MockMetaCallbackResult struct {
 	Entry *bcgo.BlockEntry
 	Meta  *spacego.Meta
 }
 
+type MockSharedMetaCallbackResult struct {
+	Owner string
+	Entry *bcgo.BlockEntry
+	Meta  *spacego.Meta
+}
+
+type MockPreviewCallbackResult struct {
+	Entry   *bcgo.BlockEntry
+	Preview *spacego.Preview
+}
+
+type MockTagCallbackResult struct {
+	Entry *bcgo.BlockEntry
+	Tag   *spacego.Tag
+}
+
+type MockHistoryCallbackResult struct {
+	Block []byte
+	Entry *bcgo.BlockEntry
+	Delta *spacego.Delta
+}
+
 type MockRegistrationCallbackResult struct {
 	Entry        *bcgo.BlockEntry
 	Registration *financego.Registration
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"aletheiaware.com/bcgo"
+	"errors"
+	"sync"
+	"time"
+)
+
+// FakeNetwork is a stand-in for the peers of a node, whose responses are configured by the test.
+type FakeNetwork struct {
+	// Blocked makes every call wait forever, as if peers never respond.
+	Blocked bool
+	// Delay makes every call take the given time, as if peers are slow to respond.
+	Delay time.Duration
+	// HeadError, BlockError, and BroadcastError are returned by Head, Block, and Broadcast respectively.
+	HeadError, BlockError, BroadcastError error
+
+	lock                      sync.Mutex
+	broadcasts, maxBroadcasts int
+}
+
+// MaxConcurrentBroadcasts returns the most broadcasts which were in progress at the same time.
+func (n *FakeNetwork) MaxConcurrentBroadcasts() int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.maxBroadcasts
+}
+
+// NewBlockingNetwork returns a network whose peers never respond.
+func NewBlockingNetwork() *FakeNetwork {
+	return &FakeNetwork{
+		Blocked: true,
+	}
+}
+
+// NewFailingNetwork returns a network whose peers cannot be reached.
+func NewFailingNetwork() *FakeNetwork {
+	err := errors.New("Connection refused")
+	return &FakeNetwork{
+		HeadError:      err,
+		BlockError:     err,
+		BroadcastError: err,
+	}
+}
+
+// NewAcceptingNetwork returns a network whose peers accept every broadcast, but have no channels.
+func NewAcceptingNetwork() *FakeNetwork {
+	return &FakeNetwork{
+		HeadError:  errors.New("No such channel"),
+		BlockError: errors.New("No such block"),
+	}
+}
+
+func (n *FakeNetwork) Head(string) (*bcgo.Reference, error) {
+	n.wait()
+	return nil, n.HeadError
+}
+
+func (n *FakeNetwork) Block(*bcgo.Reference) (*bcgo.Block, error) {
+	n.wait()
+	return nil, n.BlockError
+}
+
+func (n *FakeNetwork) Broadcast(bcgo.Channel, bcgo.Cache, []byte, *bcgo.Block) error {
+	n.lock.Lock()
+	n.broadcasts++
+	if n.broadcasts > n.maxBroadcasts {
+		n.maxBroadcasts = n.broadcasts
+	}
+	n.lock.Unlock()
+	defer func() {
+		n.lock.Lock()
+		n.broadcasts--
+		n.lock.Unlock()
+	}()
+	n.wait()
+	return n.BroadcastError
+}
+
+func (n *FakeNetwork) wait() {
+	if n.Blocked {
+		select {}
+	}
+	time.Sleep(n.Delay)
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"aletheiaware.com/spaceclientgo/internal/notify"
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// NewNotifyingPeer returns an in-process stand-in for a peer which accepts subscriptions from a TCP notifier, and is closed when the test completes.
+func NewNotifyingPeer(t *testing.T) *NotifyingPeer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &NotifyingPeer{
+		listener:      listener,
+		subscribers:   make(map[string][]net.Conn),
+		subscriptions: make(map[string]int),
+		subscribed:    make(chan struct{}, 1),
+	}
+	go p.accept()
+	t.Cleanup(p.Close)
+	return p
+}
+
+type NotifyingPeer struct {
+	sync.Mutex
+	listener    net.Listener
+	subscribers map[string][]net.Conn
+	// subscriptions counts the subscriptions to each channel which have not been awaited.
+	subscriptions map[string]int
+	// subscribed signals a waiter that a subscription was made, without blocking the peer if none is waiting.
+	subscribed chan struct{}
+}
+
+// Address returns the host and port the peer is listening on.
+func (p *NotifyingPeer) Address() string {
+	return p.listener.Addr().String()
+}
+
+// AwaitSubscription waits for a subscription to the channel with the given name which has not already been awaited.
+func (p *NotifyingPeer) AwaitSubscription(t *testing.T, channel string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		p.Lock()
+		if p.subscriptions[channel] > 0 {
+			p.subscriptions[channel]--
+			p.Unlock()
+			return
+		}
+		p.Unlock()
+		select {
+		case <-p.subscribed:
+		case <-timeout:
+			t.Fatalf("Expected subscription to %s", channel)
+		}
+	}
+}
+
+// Notify notifies all subscribers of the channel with the given name of its new head.
+func (p *NotifyingPeer) Notify(channel string, head []byte) {
+	p.Lock()
+	defer p.Unlock()
+	for _, conn := range p.subscribers[channel] {
+		notify.WriteNotification(conn, channel, head)
+	}
+}
+
+// Disconnect closes the connections of all subscribers.
+func (p *NotifyingPeer) Disconnect() {
+	p.Lock()
+	defer p.Unlock()
+	for channel, conns := range p.subscribers {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		delete(p.subscribers, channel)
+	}
+}
+
+// Close stops the peer listening and disconnects all subscribers.
+func (p *NotifyingPeer) Close() {
+	p.listener.Close()
+	p.Disconnect()
+}
+
+func (p *NotifyingPeer) accept() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.serve(conn)
+	}
+}
+
+func (p *NotifyingPeer) serve(conn net.Conn) {
+	channel, err := notify.ReadSubscription(bufio.NewReader(conn))
+	if err != nil {
+		conn.Close()
+		return
+	}
+	p.Lock()
+	p.subscribers[channel] = append(p.subscribers[channel], conn)
+	p.subscriptions[channel]++
+	p.Unlock()
+	select {
+	case p.subscribed <- struct{}{}:
+	default:
+		// A waiter has already been signalled
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Delete moves the file with the given meta ID to the trash.
+// A tombstone version of the meta data is written so the file is hidden but can be restored.
+func (c *spaceClient) Delete(node bcgo.Node, listener bcgo.MiningListener, metaId []byte) error {
+	metas := c.openMetas(node)
+	f, err := readFile(metas, node.Cache(), node.Network(), node.Account(), metaId)
+	if err != nil {
+		return err
+	}
+	if f == nil || f.deleted() {
+		return c.errNotOwned(node, metaId)
+	}
+	if err := c.writeMeta(node, metas, f, &spacego.Meta{
+		Name: f.latest().meta.Name,
+		Type: MIME_TYPE_DELETED,
+	}); err != nil {
+		return err
+	}
+	return c.mine(node, metas, listener)
+}
+
+// Restore moves the file with the given meta ID out of the trash.
+func (c *spaceClient) Restore(node bcgo.Node, listener bcgo.MiningListener, metaId []byte) error {
+	metas := c.openMetas(node)
+	f, err := readFile(metas, node.Cache(), node.Network(), node.Account(), metaId)
+	if err != nil {
+		return err
+	}
+	if f == nil || !f.deleted() || f.purged() {
+		return fmt.Errorf("%w in trash: %s", ErrFileNotFound, base64.RawURLEncoding.EncodeToString(metaId))
+	}
+	live := f.live()
+	if live == nil {
+		return fmt.Errorf("Could not find meta data: %s", base64.RawURLEncoding.EncodeToString(metaId))
+	}
+	if err := c.writeMeta(node, metas, f, live.meta); err != nil {
+		return err
+	}
+	return c.mine(node, metas, listener)
+}
+
+// Trash triggers the callback with the meta data of each file in the trash, as it was before it was deleted.
+func (c *spaceClient) Trash(node bcgo.Node, callback spacego.MetaCallback) error {
+	metas := c.openMetas(node)
+	files, err := readFiles(metas, node.Cache(), node.Network(), node.Account())
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if !f.deleted() || f.purged() {
+			continue
+		}
+		live := f.live()
+		if live == nil {
+			continue
+		}
+		if err := callback(f.entry(), live.meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmptyTrash permanently removes all files in the trash so they can no longer be restored.
+// Records are immutable so the content remains in the blockchain, but the files are no longer listed or restorable.
+func (c *spaceClient) EmptyTrash(node bcgo.Node, listener bcgo.MiningListener) error {
+	metas := c.openMetas(node)
+	files, err := readFiles(metas, node.Cache(), node.Network(), node.Account())
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, f := range files {
+		if !f.deleted() || f.purged() {
+			continue
+		}
+		if err := c.writeMeta(node, metas, f, &spacego.Meta{
+			Name: f.latest().meta.Name,
+			Type: MIME_TYPE_PURGED,
+		}); err != nil {
+			return err
+		}
+		count++
+	}
+	if count == 0 {
+		// Trash is empty
+		return nil
+	}
+	return c.mine(node, metas, listener)
+}
+
+// openMetas opens and refreshes the meta channel of the node's account.
+func (c *spaceClient) openMetas(node bcgo.Node) bcgo.Channel {
+	alias := node.Account().Alias()
+	metas := node.OpenChannel(spacego.MetaChannelName(alias), func() bcgo.Channel {
+		return spacego.OpenMetaChannel(alias)
+	})
+	c.refresh(node, metas)
+	return metas
+}
+
+// fileState records whether a file was in the trash, and the compression applied to its content, when the account's meta channel had the given head.
+type fileState struct {
+	head        []byte
+	deleted     bool
+	compression string
+}
+
+// checkFile returns an error wrapping ErrFileNotFound if the account has no file with the given meta ID, or the file is in the trash.
+func (c *spaceClient) checkFile(node bcgo.Node, metaId []byte) error {
+	_, err := c.stateForHash(node, metaId)
+	return err
+}
+
+// stateForHash returns the state of the file with the given meta ID, or an error wrapping ErrFileNotFound if the account has no such file, or the file is in the trash.
+// The state of the account's own files is cached until its meta channel changes, and of files shared with it until the owner's meta channel changes too.
+func (c *spaceClient) stateForHash(node bcgo.Node, metaId []byte) (*fileState, error) {
+	metas := c.openMetas(node)
+	head := metas.Head()
+	key := contentKey(node, metaId)
+	if s, ok := c.files.Load(key); ok && head != nil && bytes.Equal(s.(*fileState).head, head) {
+		if s.(*fileState).deleted {
+			return nil, c.errFileNotFound(node, metaId)
+		}
+		return s.(*fileState), nil
+	}
+	f, err := readFile(metas, node.Cache(), node.Network(), node.Account(), metaId)
+	if err != nil {
+		return nil, err
+	}
+	if f != nil {
+		s := &fileState{
+			head:        head,
+			deleted:     f.deleted(),
+			compression: f.compression(),
+		}
+		c.files.Store(key, s)
+		if s.deleted {
+			return nil, c.errFileNotFound(node, metaId)
+		}
+		return s, nil
+	}
+	// File may have been shared by another alias, whose state is cached until either meta channel changes
+	s, err := c.sharedFile(node, metas, metaId)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, c.errFileNotFound(node, metaId)
+	}
+	return &fileState{
+		head:        head,
+		compression: s.file.compression(),
+	}, nil
+}
+
+// reservedType returns an error if the given MIME type is reserved for marking files in the trash, or holds the parameter recording the compression of a file's content.
+func reservedType(mime string) error {
+	switch mime {
+	case MIME_TYPE_DELETED, MIME_TYPE_PURGED:
+		return fmt.Errorf("Reserved MIME type: %s", mime)
+	}
+	if strings.Contains(mime, COMPRESSION_PARAMETER) {
+		return fmt.Errorf("Reserved MIME type parameter: %s", mime)
+	}
+	return nil
+}
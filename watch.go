@@ -0,0 +1,258 @@
+/*
+ * Copyright 2021 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spaceclientgo
+
+import (
+	"aletheiaware.com/bcgo"
+	"aletheiaware.com/spacego"
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileChange is a delta applied to a file.
+type FileChange struct {
+	// Head is the hash of the head block of the file's delta channel after the update containing the delta.
+	Head []byte
+	// Block is the hash of the block containing the delta.
+	Block []byte
+	// Author is the alias which wrote the delta.
+	Author string
+	Entry  *bcgo.BlockEntry
+	Delta  *spacego.Delta
+}
+
+// FileChangeCallback is triggered with each change to a watched file.
+// Returning an error stops the watch.
+type FileChangeCallback func(*FileChange) error
+
+// FileWatch is a handle to a watch started by WatchFileChanges.
+type FileWatch interface {
+	// Stop ends the watch, changes not yet delivered are discarded.
+	Stop()
+	// Done returns a channel which is closed once the watch has ended.
+	Done() <-chan struct{}
+	// Err returns the error which ended the watch, either from the callback or the context, or nil while the watch is running.
+	Err() error
+}
+
+type fileWatch struct {
+	sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	signal  chan struct{}
+	pending []*FileChange
+	err     error
+}
+
+func (w *fileWatch) Stop() {
+	w.cancel()
+}
+
+func (w *fileWatch) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *fileWatch) Err() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.err
+}
+
+// enqueue adds the given changes to those waiting to be delivered.
+func (w *fileWatch) enqueue(changes []*FileChange) {
+	w.Lock()
+	w.pending = append(w.pending, changes...)
+	w.Unlock()
+	select {
+	case w.signal <- struct{}{}:
+	default:
+		// Delivery already signalled
+	}
+}
+
+// deliver triggers the callback with each change in the order enqueued until the watch ends.
+func (w *fileWatch) deliver(callback FileChangeCallback) {
+	defer close(w.done)
+	for {
+		select {
+		case <-w.ctx.Done():
+			w.stop(w.ctx.Err())
+			return
+		case <-w.signal:
+		}
+		w.Lock()
+		changes := w.pending
+		w.pending = nil
+		w.Unlock()
+		for _, c := range changes {
+			if w.ctx.Err() != nil {
+				break
+			}
+			if err := callback(c); err != nil {
+				w.stop(err)
+				return
+			}
+		}
+	}
+}
+
+func (w *fileWatch) stop(err error) {
+	w.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.Unlock()
+	w.cancel()
+}
+
+// WatchFileChanges triggers the given callback with each delta applied to the file with the given meta ID, until the context is done, the watch is stopped, or the callback returns an error.
+// Changes are delivered one at a time, in the order they were applied.
+// Files re-keyed by Revoke are followed into the channel they were forwarded to, the change forwarding the file deletes its content and the first change in the new channel inserts a snapshot of it.
+// Changes which could not be read are logged and read again with the next update, or after a delay.
+func (c *spaceClient) WatchFileChanges(ctx context.Context, node bcgo.Node, metaId []byte, callback FileChangeCallback) (FileWatch, error) {
+	deltas, err := c.deltaChannel(node, metaId)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := c.compressionForHash(node, metaId)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w := &fileWatch{
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		signal: make(chan struct{}, 1),
+	}
+	var (
+		lock sync.Mutex
+		// Changes up to the current head have already been applied
+		head = deltas.Head()
+		// Only the channel currently holding the file is watched
+		channelCtx, stopChannel = context.WithCancel(ctx)
+		retrying                bool
+		initial                 = time.Second
+		limit                   = time.Hour
+		delay                   = initial
+	)
+	var update func()
+	update = func() {
+		lock.Lock()
+		defer lock.Unlock()
+		if ctx.Err() != nil {
+			// Watch has already ended
+			return
+		}
+		for {
+			changes, err := c.readChanges(node, deltas, compression, head)
+			if err != nil {
+				c.logger.Warn("Could not read file changes", "channel", deltas.Name(), "error", err)
+				if !retrying {
+					retrying = true
+					time.AfterFunc(delay, func() {
+						lock.Lock()
+						retrying = false
+						lock.Unlock()
+						update()
+					})
+					if delay *= 2; delay > limit {
+						delay = limit
+					}
+				}
+				return
+			}
+			delay = initial
+			var next string
+			for i, change := range changes {
+				if next = forward(deltas, change.Entry); next != "" {
+					// Remaining changes in this channel are superseded
+					changes = changes[:i+1]
+					break
+				}
+			}
+			if len(changes) > 0 {
+				w.enqueue(changes)
+			}
+			if next == "" {
+				head = deltas.Head()
+				return
+			}
+			// Follow the file into the channel it was forwarded to
+			id := strings.TrimPrefix(next, spacego.DeltaChannelName(""))
+			deltas = node.OpenChannel(next, func() bcgo.Channel {
+				return spacego.OpenDeltaChannel(id)
+			})
+			c.refresh(node, deltas)
+			head = nil
+			stopChannel()
+			channelCtx, stopChannel = context.WithCancel(ctx)
+			deltas.AddTrigger(update)
+			c.watch(channelCtx, node, deltas)
+		}
+	}
+	deltas.AddTrigger(update)
+	go w.deliver(callback)
+	c.watch(channelCtx, node, deltas)
+	return w, nil
+}
+
+// readChanges returns the changes in the given delta channel made after the block with the given hash, with the bytes inserted decompressed with the given compression.
+func (c *spaceClient) readChanges(node bcgo.Node, deltas bcgo.Channel, compression string, previous []byte) ([]*FileChange, error) {
+	head := deltas.Head()
+	blocks := make(map[string][]byte)
+	if err := bcgo.Iterate(deltas.Name(), head, nil, node.Cache(), node.Network(), func(hash []byte, block *bcgo.Block) error {
+		if bytes.Equal(hash, previous) {
+			return errStopped
+		}
+		for _, e := range block.Entry {
+			blocks[string(e.RecordHash)] = hash
+		}
+		return nil
+	}); err != nil && err != errStopped {
+		return nil, err
+	}
+	var changes []*FileChange
+	if len(blocks) == 0 {
+		return changes, nil
+	}
+	if err := spacego.IterateDeltas(node, deltas, func(entry *bcgo.BlockEntry, delta *spacego.Delta) error {
+		block, ok := blocks[string(entry.RecordHash)]
+		if !ok {
+			// Delta was already applied
+			return nil
+		}
+		if err := c.decompressDelta(compression, delta); err != nil {
+			return err
+		}
+		changes = append(changes, &FileChange{
+			Head:   head,
+			Block:  block,
+			Author: entry.Record.Creator,
+			Entry:  entry,
+			Delta:  delta,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}